@@ -0,0 +1,418 @@
+package fakenode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// stateReceiverAddress is the Bor genesis contract executing the state-sync events.
+	stateReceiverAddress = "0x0000000000000000000000000000000000001001"
+	// stateCommittedTopic is the topic of the StateCommitted(uint256,bool) event.
+	stateCommittedTopic = "0x5a22725590b0a51c923940223f7458512164b1113359a735e86e7f27f44791ee"
+	zeroAddress         = "0x0000000000000000000000000000000000000000"
+)
+
+// JSON-RPC error codes answered by the fake Bor node.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeServerError    = -32000
+)
+
+// Error is a JSON-RPC error. Handlers return one to answer a given code, other errors
+// are answered with code -32000.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Handler answers the calls of a JSON-RPC method.
+type Handler func(params []json.RawMessage) (interface{}, error)
+
+// Bor is a fake Bor node serving a Chain over HTTP JSON-RPC, single and batched.
+type Bor struct {
+	*httptest.Server
+	chain  *Chain
+	faults *faults
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// NewBor starts a fake Bor node serving chain. It answers eth_chainId, eth_blockNumber,
+// eth_getBlockByNumber, eth_getBlockByHash, bor_getAuthor, eth_getLogs for the
+// StateCommitted events and eth_getTransactionByHash for the state-sync transactions.
+func NewBor(chain *Chain) *Bor {
+	b := &Bor{chain: chain, faults: newFaults()}
+	b.handlers = map[string]Handler{
+		"eth_chainId":              b.chainID,
+		"eth_blockNumber":          b.blockNumber,
+		"eth_getBlockByNumber":     b.getBlockByNumber,
+		"eth_getBlockByHash":       b.getBlockByHash,
+		"bor_getAuthor":            b.getAuthor,
+		"eth_getLogs":              b.getLogs,
+		"eth_getTransactionByHash": b.getTransactionByHash,
+	}
+	b.Server = httptest.NewServer(b)
+	return b
+}
+
+// Handle answers method with handler, replacing its built-in handler if any.
+func (b *Bor) Handle(method string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[method] = handler
+}
+
+// InjectFault injects fault in the answers to the calls containing method, or to every
+// call when method is empty.
+func (b *Bor) InjectFault(method string, fault Fault) {
+	b.faults.inject(method, fault)
+}
+
+func (b *Bor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+	var requests []rpcRequest
+	if batch {
+		err = json.Unmarshal(body, &requests)
+	} else {
+		requests = make([]rpcRequest, 1)
+		err = json.Unmarshal(body, &requests[0])
+	}
+	if err != nil {
+		writeJSON(w, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{codeParseError, "parse error"}})
+		return
+	}
+
+	fault, ok := b.faults.take(func(method string) bool {
+		for _, request := range requests {
+			if request.Method == method {
+				return true
+			}
+		}
+		return false
+	})
+	if ok && fault.apply(w, r) {
+		return
+	}
+
+	responses := make([]rpcResponse, len(requests))
+	for i, request := range requests {
+		responses[i] = b.call(request)
+	}
+	if batch {
+		writeJSON(w, responses)
+		return
+	}
+	writeJSON(w, responses[0])
+}
+
+func (b *Bor) call(request rpcRequest) rpcResponse {
+	response := rpcResponse{JSONRPC: "2.0", ID: request.ID}
+	if response.ID == nil {
+		response.ID = json.RawMessage("null")
+	}
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		response.Error = &Error{codeInvalidRequest, "invalid request"}
+		return response
+	}
+
+	b.mu.Lock()
+	handler, ok := b.handlers[request.Method]
+	b.mu.Unlock()
+	if !ok {
+		response.Error = &Error{codeMethodNotFound, fmt.Sprintf("the method %s does not exist/is not available", request.Method)}
+		return response
+	}
+
+	var params []json.RawMessage
+	if len(request.Params) > 0 && string(request.Params) != "null" {
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			response.Error = &Error{codeInvalidParams, "non-array args"}
+			return response
+		}
+	}
+	result, err := handler(params)
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{codeServerError, err.Error()}
+		}
+		response.Error = rpcErr
+		return response
+	}
+	response.Result, err = json.Marshal(result)
+	if err != nil {
+		response.Error = &Error{codeServerError, err.Error()}
+	}
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func hexUint(value uint64) string {
+	return "0x" + strconv.FormatUint(value, 16)
+}
+
+// hexWord encodes value as a 32 bytes word, like the topics and data of the logs.
+func hexWord(value uint64) string {
+	return fmt.Sprintf("0x%064x", value)
+}
+
+// param decodes the i-th parameter of a call into value.
+func param(params []json.RawMessage, i int, value interface{}) error {
+	if i >= len(params) {
+		return &Error{codeInvalidParams, fmt.Sprintf("missing value for required argument %d", i)}
+	}
+	if err := json.Unmarshal(params[i], value); err != nil {
+		return &Error{codeInvalidParams, fmt.Sprintf("invalid argument %d: %v", i, err)}
+	}
+	return nil
+}
+
+// resolveBlockNumber resolves a block tag or hex number.
+func (b *Bor) resolveBlockNumber(tag string) (uint64, error) {
+	switch tag {
+	case "latest", "pending", "safe", "finalized":
+		return b.chain.Head().Number, nil
+	case "earliest":
+		return 0, nil
+	}
+	number, err := strconv.ParseUint(strings.TrimPrefix(tag, "0x"), 16, 64)
+	if err != nil || !strings.HasPrefix(tag, "0x") {
+		return 0, &Error{codeInvalidParams, fmt.Sprintf("invalid argument 0: invalid block number %q", tag)}
+	}
+	return number, nil
+}
+
+func (b *Bor) blockParam(params []json.RawMessage) (Block, bool, error) {
+	var tag string
+	if err := param(params, 0, &tag); err != nil {
+		return Block{}, false, err
+	}
+	number, err := b.resolveBlockNumber(tag)
+	if err != nil {
+		return Block{}, false, err
+	}
+	block, ok := b.chain.block(number)
+	return block, ok, nil
+}
+
+func blockJSON(block Block) map[string]interface{} {
+	transactions := block.Transactions
+	if transactions == nil {
+		transactions = []string{}
+	}
+	return map[string]interface{}{
+		"number":       hexUint(block.Number),
+		"hash":         block.Hash,
+		"parentHash":   block.ParentHash,
+		"timestamp":    hexUint(block.Timestamp),
+		"miner":        zeroAddress,
+		"transactions": transactions,
+	}
+}
+
+func (b *Bor) chainID(params []json.RawMessage) (interface{}, error) {
+	return hexUint(b.chain.chainID), nil
+}
+
+func (b *Bor) blockNumber(params []json.RawMessage) (interface{}, error) {
+	return hexUint(b.chain.Head().Number), nil
+}
+
+func (b *Bor) getBlockByNumber(params []json.RawMessage) (interface{}, error) {
+	block, ok, err := b.blockParam(params)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return blockJSON(block), nil
+}
+
+func (b *Bor) getBlockByHash(params []json.RawMessage) (interface{}, error) {
+	var hash string
+	if err := param(params, 0, &hash); err != nil {
+		return nil, err
+	}
+	for number := uint64(0); ; number++ {
+		block, ok := b.chain.block(number)
+		if !ok {
+			return nil, nil
+		}
+		if strings.EqualFold(block.Hash, hash) {
+			return blockJSON(block), nil
+		}
+	}
+}
+
+func (b *Bor) getAuthor(params []json.RawMessage) (interface{}, error) {
+	block, ok, err := b.blockParam(params)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &Error{codeServerError, "unknown block"}
+	}
+	return block.Author, nil
+}
+
+// getLogs answers the StateCommitted logs of the state-sync events in the filtered blocks.
+func (b *Bor) getLogs(params []json.RawMessage) (interface{}, error) {
+	var filter struct {
+		FromBlock string          `json:"fromBlock"`
+		ToBlock   string          `json:"toBlock"`
+		Address   json.RawMessage `json:"address"`
+		Topics    json.RawMessage `json:"topics"`
+	}
+	if err := param(params, 0, &filter); err != nil {
+		return nil, err
+	}
+	from, to := uint64(0), b.chain.Head().Number
+	var err error
+	if filter.FromBlock != "" {
+		if from, err = b.resolveBlockNumber(filter.FromBlock); err != nil {
+			return nil, err
+		}
+	}
+	if filter.ToBlock != "" {
+		if to, err = b.resolveBlockNumber(filter.ToBlock); err != nil {
+			return nil, err
+		}
+	}
+	logs := []map[string]interface{}{}
+	if !filterMatches(filter.Address, stateReceiverAddress) || !filterMatches(firstTopic(filter.Topics), stateCommittedTopic) {
+		return logs, nil
+	}
+
+	for _, stateSync := range b.chain.stateSyncsInBlocks(from, to) {
+		block, _ := b.chain.block(stateSync.BlockNumber)
+		success := uint64(0)
+		if stateSync.Success {
+			success = 1
+		}
+		logs = append(logs, map[string]interface{}{
+			"address":          stateReceiverAddress,
+			"topics":           []string{stateCommittedTopic, hexWord(stateSync.ID)},
+			"data":             hexWord(success),
+			"blockNumber":      hexUint(stateSync.BlockNumber),
+			"blockHash":        block.Hash,
+			"transactionHash":  stateSync.TxHash,
+			"transactionIndex": hexUint(uint64(len(block.Transactions))),
+			"logIndex":         hexUint(uint64(len(logs))),
+			"removed":          false,
+		})
+	}
+	return logs, nil
+}
+
+// filterMatches reports whether value is allowed by a filter criterion, absent or null
+// for any value, a value, or a list of alternative values.
+func filterMatches(criterion json.RawMessage, value string) bool {
+	if len(criterion) == 0 || string(criterion) == "null" {
+		return true
+	}
+	var single string
+	if json.Unmarshal(criterion, &single) == nil {
+		return strings.EqualFold(single, value)
+	}
+	var alternatives []string
+	if json.Unmarshal(criterion, &alternatives) != nil {
+		return false
+	}
+	for _, alternative := range alternatives {
+		if strings.EqualFold(alternative, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstTopic returns the criterion of the first topic of a filter.
+func firstTopic(topics json.RawMessage) json.RawMessage {
+	var positions []json.RawMessage
+	if json.Unmarshal(topics, &positions) != nil || len(positions) == 0 {
+		return nil
+	}
+	return positions[0]
+}
+
+// getTransactionByHash answers the state-sync transactions, listing the events they
+// execute in stateSyncData when they are marked as Executed.
+func (b *Bor) getTransactionByHash(params []json.RawMessage) (interface{}, error) {
+	var hash string
+	if err := param(params, 0, &hash); err != nil {
+		return nil, err
+	}
+	stateSyncs := b.chain.stateSyncsOfTx(hash)
+	if len(stateSyncs) == 0 {
+		return nil, nil
+	}
+	block, _ := b.chain.block(stateSyncs[0].BlockNumber)
+	tx := map[string]interface{}{
+		"hash":             stateSyncs[0].TxHash,
+		"blockNumber":      hexUint(block.Number),
+		"blockHash":        block.Hash,
+		"transactionIndex": hexUint(uint64(len(block.Transactions))),
+		"from":             zeroAddress,
+		"to":               zeroAddress,
+		"input":            "0x",
+		"nonce":            "0x0",
+		"value":            "0x0",
+		"gas":              "0x0",
+		"gasPrice":         "0x0",
+		"type":             "0x0",
+	}
+
+	var executed []map[string]interface{}
+	for _, stateSync := range stateSyncs {
+		if stateSync.Executed {
+			executed = append(executed, map[string]interface{}{
+				"id":       stateSync.ID,
+				"contract": stateSync.Contract,
+				"data":     stateSync.Data,
+				"txHash":   stateSync.L1TxHash,
+			})
+		}
+	}
+	if executed != nil {
+		tx["stateSyncData"] = executed
+	}
+	return tx, nil
+}
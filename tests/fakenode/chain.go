@@ -0,0 +1,231 @@
+// Package fakenode serves a scriptable chain over in-process Bor JSON-RPC and Heimdall
+// REST endpoints, so that the test harnesses of this repository can be unit-tested
+// without a network.
+package fakenode
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// blockTime is the number of seconds between the blocks added by AddBlocks.
+const blockTime = 2
+
+// Block is a block of the fake Bor chain.
+type Block struct {
+	Number     uint64
+	Hash       string
+	ParentHash string
+	Timestamp  uint64
+	// Author is the signer returned by bor_getAuthor for the block.
+	Author string
+	// Transactions are the hashes of the transactions of the block.
+	Transactions []string
+}
+
+// Producer is a validator selected to produce the blocks of a span.
+type Producer struct {
+	ValID  uint64
+	Signer string
+}
+
+// Span is a range of blocks Heimdall assigned to producers.
+type Span struct {
+	ID         uint64
+	StartBlock uint64
+	EndBlock   uint64
+	Producers  []Producer
+}
+
+// Downtime is the range of blocks during which a producer planned to be down.
+type Downtime struct {
+	StartBlock uint64
+	EndBlock   uint64
+}
+
+// StateSync is an L1 state-sync event, recorded by Heimdall and committed by Bor in the
+// state-sync transaction TxHash of block BlockNumber.
+type StateSync struct {
+	ID          uint64
+	BlockNumber uint64
+	TxHash      string
+	Contract    string
+	// Data is the 0x prefixed hex encoded payload of the event.
+	Data     string
+	L1TxHash string
+	LogIndex uint64
+	Success  bool
+	// Executed lists the event in the stateSyncData of the state-sync transaction, as
+	// nodes implementing PIP-74 do.
+	Executed bool
+}
+
+// Checkpoint is a checkpoint of Bor blocks submitted by Heimdall.
+type Checkpoint struct {
+	ID         uint64
+	Proposer   string
+	StartBlock uint64
+	EndBlock   uint64
+	// RootHash is the 0x prefixed hex encoded root of the checkpointed blocks.
+	RootHash string
+}
+
+// Chain is the state served by the fake nodes. It is safe to script while they serve it,
+// e.g. to add blocks while a harness waits for them.
+type Chain struct {
+	mu          sync.Mutex
+	chainID     uint64
+	blocks      []Block
+	spans       map[uint64]Span
+	downtimes   map[uint64]Downtime
+	stateSyncs  []StateSync
+	checkpoints []Checkpoint
+}
+
+// NewChain returns a chain holding only its genesis block.
+func NewChain(chainID uint64) *Chain {
+	return &Chain{
+		chainID:   chainID,
+		blocks:    []Block{{Hash: blockHash(0), ParentHash: blockHash(-1), Timestamp: uint64(time.Now().Unix())}},
+		spans:     make(map[uint64]Span),
+		downtimes: make(map[uint64]Downtime),
+	}
+}
+
+// blockHash is the hash given to the blocks added without one.
+func blockHash(number int64) string {
+	return fmt.Sprintf("0x%064x", number+1)
+}
+
+// AddBlock appends block to the chain and returns it. Its number and parent hash are
+// set, as well as its hash and timestamp when they are empty.
+func (c *Chain) AddBlock(block Block) Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	head := c.blocks[len(c.blocks)-1]
+	block.Number = head.Number + 1
+	block.ParentHash = head.Hash
+	if block.Hash == "" {
+		block.Hash = blockHash(int64(block.Number))
+	}
+	if block.Timestamp == 0 {
+		block.Timestamp = head.Timestamp + blockTime
+	}
+	c.blocks = append(c.blocks, block)
+	return block
+}
+
+// AddBlocks appends n empty blocks sealed by author.
+func (c *Chain) AddBlocks(n int, author string) {
+	for i := 0; i < n; i++ {
+		c.AddBlock(Block{Author: author})
+	}
+}
+
+// Head returns the most recent block.
+func (c *Chain) Head() Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blocks[len(c.blocks)-1]
+}
+
+// block returns the block number, if the chain reached it.
+func (c *Chain) block(number uint64) (Block, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if number >= uint64(len(c.blocks)) {
+		return Block{}, false
+	}
+	return c.blocks[number], true
+}
+
+// AddSpan adds span, replacing the span with the same ID.
+func (c *Chain) AddSpan(span Span) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spans[span.ID] = span
+}
+
+func (c *Chain) span(id uint64) (Span, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	span, ok := c.spans[id]
+	return span, ok
+}
+
+// SetPlannedDowntime plans a downtime for the producer with the given validator ID.
+func (c *Chain) SetPlannedDowntime(producerID uint64, downtime Downtime) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.downtimes[producerID] = downtime
+}
+
+func (c *Chain) plannedDowntime(producerID uint64) (Downtime, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	downtime, ok := c.downtimes[producerID]
+	return downtime, ok
+}
+
+// AddStateSync records a state-sync event in Heimdall and commits it in Bor.
+func (c *Chain) AddStateSync(stateSync StateSync) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stateSyncs = append(c.stateSyncs, stateSync)
+}
+
+func (c *Chain) stateSync(id uint64) (StateSync, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, stateSync := range c.stateSyncs {
+		if stateSync.ID == id {
+			return stateSync, true
+		}
+	}
+	return StateSync{}, false
+}
+
+// stateSyncsInBlocks returns the state-sync events committed in blocks from to to.
+func (c *Chain) stateSyncsInBlocks(from, to uint64) []StateSync {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var stateSyncs []StateSync
+	for _, stateSync := range c.stateSyncs {
+		if stateSync.BlockNumber >= from && stateSync.BlockNumber <= to {
+			stateSyncs = append(stateSyncs, stateSync)
+		}
+	}
+	return stateSyncs
+}
+
+// stateSyncsOfTx returns the state-sync events committed by a transaction.
+func (c *Chain) stateSyncsOfTx(txHash string) []StateSync {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var stateSyncs []StateSync
+	for _, stateSync := range c.stateSyncs {
+		if strings.EqualFold(stateSync.TxHash, txHash) {
+			stateSyncs = append(stateSyncs, stateSync)
+		}
+	}
+	return stateSyncs
+}
+
+// AddCheckpoint adds checkpoint, the last one added being the latest.
+func (c *Chain) AddCheckpoint(checkpoint Checkpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkpoints = append(c.checkpoints, checkpoint)
+}
+
+func (c *Chain) latestCheckpoint() (Checkpoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.checkpoints) == 0 {
+		return Checkpoint{}, false
+	}
+	return c.checkpoints[len(c.checkpoints)-1], true
+}
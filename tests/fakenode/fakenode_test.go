@@ -0,0 +1,147 @@
+package fakenode
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, url, body string) (int, string) {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", body, err)
+	}
+	defer resp.Body.Close()
+	answer, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading answer to %s: %v", body, err)
+	}
+	return resp.StatusCode, strings.TrimSpace(string(answer))
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	answer, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading answer of %s: %v", url, err)
+	}
+	return resp.StatusCode, strings.TrimSpace(string(answer))
+}
+
+func TestBorBatch(t *testing.T) {
+	chain := NewChain(137)
+	chain.AddBlocks(3, "0xaa")
+	bor := NewBor(chain)
+	defer bor.Close()
+
+	_, answer := post(t, bor.URL, `[
+		{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1},
+		{"jsonrpc":"2.0","method":"bor_getAuthor","params":["0x2"],"id":2},
+		{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["0x9",false],"id":3},
+		{"jsonrpc":"2.0","method":"eth_unknown","params":[],"id":4}
+	]`)
+	var responses []rpcResponse
+	if err := json.Unmarshal([]byte(answer), &responses); err != nil {
+		t.Fatalf("invalid batch answer %s: %v", answer, err)
+	}
+	if len(responses) != 4 {
+		t.Fatalf("expected 4 responses, got %d", len(responses))
+	}
+	if string(responses[0].Result) != `"0x3"` {
+		t.Errorf("eth_blockNumber: expected 0x3, got %s", responses[0].Result)
+	}
+	if string(responses[1].Result) != `"0xaa"` {
+		t.Errorf("bor_getAuthor: expected 0xaa, got %s", responses[1].Result)
+	}
+	if string(responses[2].Result) != "null" {
+		t.Errorf("eth_getBlockByNumber beyond head: expected null, got %s", responses[2].Result)
+	}
+	if responses[3].Error == nil || responses[3].Error.Code != codeMethodNotFound {
+		t.Errorf("unknown method: expected code %d, got %+v", codeMethodNotFound, responses[3].Error)
+	}
+}
+
+func TestBorParseError(t *testing.T) {
+	bor := NewBor(NewChain(137))
+	defer bor.Close()
+
+	_, answer := post(t, bor.URL, `{"jsonrpc":"2.0","method":"eth_blockNumber"`)
+	if !strings.Contains(answer, `"code":-32700`) {
+		t.Errorf("expected a parse error, got %s", answer)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	chain := NewChain(137)
+	bor := NewBor(chain)
+	defer bor.Close()
+	request := `{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`
+
+	bor.InjectFault("eth_blockNumber", Fault{Status: http.StatusServiceUnavailable, Times: 2})
+	for i := 0; i < 2; i++ {
+		if status, _ := post(t, bor.URL, request); status != http.StatusServiceUnavailable {
+			t.Fatalf("call %d: expected status 503, got %d", i, status)
+		}
+	}
+	if status, _ := post(t, bor.URL, request); status != http.StatusOK {
+		t.Fatalf("expected the fault to be exhausted, got status %d", status)
+	}
+
+	bor.InjectFault("", Fault{Malformed: true, Times: 1})
+	if _, answer := post(t, bor.URL, request); json.Valid([]byte(answer)) {
+		t.Errorf("expected a malformed answer, got %s", answer)
+	}
+
+	bor.InjectFault("eth_chainId", Fault{Delay: 50 * time.Millisecond, Times: 1})
+	start := time.Now()
+	_, answer := post(t, bor.URL, `{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":1}`)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the answer to be delayed, took %s", elapsed)
+	}
+	if !strings.Contains(answer, `"result":"0x89"`) {
+		t.Errorf("expected the delayed answer to hold the result, got %s", answer)
+	}
+}
+
+func TestHeimdall(t *testing.T) {
+	chain := NewChain(137)
+	chain.AddSpan(Span{ID: 1, StartBlock: 256, EndBlock: 6655, Producers: []Producer{{ValID: 2, Signer: "0xbb"}}})
+	chain.SetPlannedDowntime(2, Downtime{StartBlock: 300, EndBlock: 400})
+	chain.AddCheckpoint(Checkpoint{ID: 7, StartBlock: 0, EndBlock: 255, RootHash: "0x0102"})
+	heimdall := NewHeimdall(chain)
+	defer heimdall.Close()
+
+	for _, test := range []struct {
+		path     string
+		status   int
+		contains string
+	}{
+		{"/bor/spans/1", http.StatusOK, `"selected_producers":[{"signer":"0xbb","val_id":"2"}]`},
+		{"/bor/spans/2", http.StatusNotFound, "span 2 not found"},
+		{"/bor/producers/planned-downtime/2", http.StatusOK, `{"downtime_range":{"end_block":"400","start_block":"300"}}`},
+		{"/bor/producers/planned-downtime/3", http.StatusNotFound, "no planned downtime found for producer id 3"},
+		{"/checkpoints/latest", http.StatusOK, `"root_hash":"AQI="`},
+	} {
+		status, answer := get(t, heimdall.URL+test.path)
+		if status != test.status || !strings.Contains(answer, test.contains) {
+			t.Errorf("%s: expected status %d with %s, got %d %s", test.path, test.status, test.contains, status, answer)
+		}
+	}
+
+	heimdall.InjectFault("/bor/spans", Fault{Status: http.StatusInternalServerError, Times: 1})
+	if status, _ := get(t, heimdall.URL+"/bor/producers/planned-downtime/2"); status != http.StatusOK {
+		t.Errorf("expected the fault to only apply to spans, got status %d", status)
+	}
+	if status, _ := get(t, heimdall.URL+"/bor/spans/1"); status != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", status)
+	}
+}
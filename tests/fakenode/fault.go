@@ -0,0 +1,86 @@
+package fakenode
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Fault is a failure injected in the answers of a fake node.
+type Fault struct {
+	// Delay is waited before answering.
+	Delay time.Duration
+	// Status, when set, is answered instead of the result, e.g. 503.
+	Status int
+	// Malformed answers a truncated JSON body.
+	Malformed bool
+	// Times is the number of requests the fault applies to, all of them when 0.
+	Times int
+}
+
+// malformedBody is answered for the requests with a Malformed fault.
+const malformedBody = `{"jsonrpc":"2.0","id":1,"result":`
+
+// faults holds the faults injected in a fake node by key, a JSON-RPC method or a REST
+// path prefix. The empty key matches every request.
+type faults struct {
+	mu    sync.Mutex
+	byKey map[string]*Fault
+}
+
+func newFaults() *faults {
+	return &faults{byKey: make(map[string]*Fault)}
+}
+
+func (f *faults) inject(key string, fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.byKey[key] = &fault
+}
+
+// take returns the fault of the key matched by the request, or else the fault matching
+// every request, consuming one of its Times.
+func (f *faults) take(match func(key string) bool) (Fault, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	matched := ""
+	for key := range f.byKey {
+		if key != "" && match(key) {
+			matched = key
+			break
+		}
+	}
+	fault, ok := f.byKey[matched]
+	if !ok {
+		return Fault{}, false
+	}
+	if fault.Times > 0 {
+		fault.Times--
+		if fault.Times == 0 {
+			delete(f.byKey, matched)
+		}
+	}
+	return *fault, true
+}
+
+// apply waits for the delay of fault, then answers the failure it injects if any and
+// reports whether it did.
+func (fault Fault) apply(w http.ResponseWriter, r *http.Request) bool {
+	if fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+	switch {
+	case fault.Status != 0:
+		http.Error(w, http.StatusText(fault.Status), fault.Status)
+		return true
+	case fault.Malformed:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(malformedBody))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,3 @@
+module fakenode
+
+go 1.24.6
@@ -0,0 +1,152 @@
+package fakenode
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+)
+
+// Heimdall is a fake Heimdall node serving a Chain over its REST API. Integers are
+// quoted and bytes base64 encoded, as the gRPC gateway of Heimdall does.
+type Heimdall struct {
+	*httptest.Server
+	chain  *Chain
+	faults *faults
+}
+
+// NewHeimdall starts a fake Heimdall node serving chain. It answers /bor/spans/{id},
+// /bor/producers/planned-downtime/{id}, /checkpoints/latest and
+// /clerk/event-records/{id}.
+func NewHeimdall(chain *Chain) *Heimdall {
+	h := &Heimdall{chain: chain, faults: newFaults()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /bor/spans/{id}", h.span)
+	mux.HandleFunc("GET /bor/producers/planned-downtime/{id}", h.plannedDowntime)
+	mux.HandleFunc("GET /checkpoints/latest", h.latestCheckpoint)
+	mux.HandleFunc("GET /clerk/event-records/{id}", h.eventRecord)
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault, ok := h.faults.take(func(prefix string) bool {
+			return strings.HasPrefix(r.URL.Path, prefix)
+		})
+		if ok && fault.apply(w, r) {
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	return h
+}
+
+// InjectFault injects fault in the answers to the requests whose path starts with
+// prefix, or to every request when prefix is empty.
+func (h *Heimdall) InjectFault(prefix string, fault Fault) {
+	h.faults.inject(prefix, fault)
+}
+
+// writeError answers an error the way the gRPC gateway does.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": 5, "message": message, "details": []string{}})
+}
+
+func quoted(value uint64) string {
+	return strconv.FormatUint(value, 10)
+}
+
+// base64Hex re-encodes 0x prefixed hex as base64.
+func base64Hex(value string) string {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		panic(fmt.Sprintf("fakenode: invalid hex %q: %v", value, err))
+	}
+	return base64.StdEncoding.EncodeToString(decoded)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid id %q", r.PathValue("id")))
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Heimdall) span(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	span, ok := h.chain.span(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("span %d not found", id))
+		return
+	}
+	producers := make([]map[string]string, len(span.Producers))
+	for i, producer := range span.Producers {
+		producers[i] = map[string]string{"val_id": quoted(producer.ValID), "signer": producer.Signer}
+	}
+	writeJSON(w, map[string]interface{}{"span": map[string]interface{}{
+		"id":                 quoted(span.ID),
+		"start_block":        quoted(span.StartBlock),
+		"end_block":          quoted(span.EndBlock),
+		"selected_producers": producers,
+		"bor_chain_id":       quoted(h.chain.chainID),
+	}})
+}
+
+func (h *Heimdall) plannedDowntime(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	downtime, ok := h.chain.plannedDowntime(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no planned downtime found for producer id %d", id))
+		return
+	}
+	writeJSON(w, map[string]interface{}{"downtime_range": map[string]string{
+		"start_block": quoted(downtime.StartBlock),
+		"end_block":   quoted(downtime.EndBlock),
+	}})
+}
+
+func (h *Heimdall) latestCheckpoint(w http.ResponseWriter, r *http.Request) {
+	checkpoint, ok := h.chain.latestCheckpoint()
+	if !ok {
+		writeError(w, http.StatusNotFound, "no checkpoint found")
+		return
+	}
+	writeJSON(w, map[string]interface{}{"checkpoint": map[string]string{
+		"id":           quoted(checkpoint.ID),
+		"proposer":     checkpoint.Proposer,
+		"start_block":  quoted(checkpoint.StartBlock),
+		"end_block":    quoted(checkpoint.EndBlock),
+		"root_hash":    base64Hex(checkpoint.RootHash),
+		"bor_chain_id": quoted(h.chain.chainID),
+	}})
+}
+
+func (h *Heimdall) eventRecord(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	stateSync, ok := h.chain.stateSync(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("event record %d not found", id))
+		return
+	}
+	writeJSON(w, map[string]interface{}{"record": map[string]string{
+		"id":           quoted(stateSync.ID),
+		"contract":     stateSync.Contract,
+		"data":         base64Hex(stateSync.Data),
+		"tx_hash":      stateSync.L1TxHash,
+		"log_index":    quoted(stateSync.LogIndex),
+		"bor_chain_id": quoted(h.chain.chainID),
+	}})
+}
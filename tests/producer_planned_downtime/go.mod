@@ -1,3 +1,7 @@
 module producer-planned-downtime
 
-go 1.24.6
\ No newline at end of file
+go 1.24.6
+
+replace fakenode => ../fakenode
+
+require fakenode v0.0.0-00010101000000-000000000000
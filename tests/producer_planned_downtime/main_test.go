@@ -0,0 +1,151 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"fakenode"
+)
+
+const (
+	producerA = "0x00000000000000000000000000000000000000aa"
+	producerB = "0x00000000000000000000000000000000000000bb"
+)
+
+// startFakeNodes points the harness at fake Bor and Heimdall nodes serving chain, with
+// the endpoints written the way kurtosis port print does, without a scheme.
+func startFakeNodes(t *testing.T, chain *fakenode.Chain) (*fakenode.Bor, *fakenode.Heimdall) {
+	t.Helper()
+	bor := fakenode.NewBor(chain)
+	heimdall := fakenode.NewHeimdall(chain)
+	t.Cleanup(func() {
+		bor.Close()
+		heimdall.Close()
+		borRPC, heimdallREST, spans = "", "", nil
+	})
+	borRPC = strings.TrimPrefix(bor.URL, "http://")
+	heimdallREST = strings.TrimPrefix(heimdall.URL, "http://")
+	return bor, heimdall
+}
+
+func TestSanitizeEndpoint(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"127.0.0.1:8545":        "127.0.0.1:8545",
+		"http://127.0.0.1:1317": "127.0.0.1:1317",
+		"\x1b[33mWARN\x1b[0m upgrade available\nhttp://127.0.0.1:32771\n": "127.0.0.1:32771",
+	} {
+		endpoint, err := sanitizeEndpoint(raw)
+		if err != nil || endpoint != expected {
+			t.Errorf("sanitizeEndpoint(%q) = %q, %v; expected %q", raw, endpoint, err, expected)
+		}
+	}
+	if _, err := sanitizeEndpoint("no endpoint"); err == nil {
+		t.Error("expected an error for an output without endpoint")
+	}
+}
+
+func TestGetSpans(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	chain.AddSpan(fakenode.Span{ID: 0, StartBlock: 0, EndBlock: 255, Producers: []fakenode.Producer{{ValID: 1, Signer: producerA}}})
+	chain.AddSpan(fakenode.Span{ID: 1, StartBlock: 256, EndBlock: 511, Producers: []fakenode.Producer{{ValID: 1, Signer: producerA}, {ValID: 2, Signer: producerB}}})
+	chain.AddSpan(fakenode.Span{ID: 2, StartBlock: 400, EndBlock: 767, Producers: []fakenode.Producer{{ValID: 2, Signer: producerB}}})
+	startFakeNodes(t, chain)
+
+	if err := getSpans(); err != nil {
+		t.Fatalf("getSpans: %v", err)
+	}
+	// Spans with several selected producers are left out
+	if len(spans) != 2 || spans[0].ID != "0" || spans[1].ID != "2" {
+		t.Fatalf("expected spans 0 and 2, got %+v", spans)
+	}
+
+	for block, expected := range map[int64]string{100: producerA, 450: producerB} {
+		author, err := getExpectedBlockAuthor(block)
+		if err != nil || author != expected {
+			t.Errorf("getExpectedBlockAuthor(%d) = %s, %v; expected %s", block, author, err, expected)
+		}
+	}
+	if _, err := getExpectedBlockAuthor(1000); err == nil {
+		t.Error("expected an error for a block no span covers")
+	}
+}
+
+func TestGetSpansHeimdallDown(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	chain.AddSpan(fakenode.Span{ID: 0, StartBlock: 0, EndBlock: 255, Producers: []fakenode.Producer{{ValID: 1, Signer: producerA}}})
+	_, heimdall := startFakeNodes(t, chain)
+
+	heimdall.InjectFault("/bor/spans", fakenode.Fault{Status: http.StatusServiceUnavailable})
+	if err := getSpans(); err == nil || !strings.Contains(err.Error(), "unexpected status 503") {
+		t.Errorf("expected an unexpected status error, got %v", err)
+	}
+}
+
+func TestGetProducerDowntimeBlocks(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	_, heimdall := startFakeNodes(t, chain)
+
+	// runSetup retries while Heimdall has not processed the downtime yet
+	_, _, err := getProducerDowntimeBlocks(2)
+	if err == nil || !strings.Contains(err.Error(), "no planned downtime found for producer id") {
+		t.Fatalf("expected no planned downtime, got %v", err)
+	}
+
+	chain.SetPlannedDowntime(2, fakenode.Downtime{StartBlock: 300, EndBlock: 390})
+	start, end, err := getProducerDowntimeBlocks(2)
+	if err != nil || start != 300 || end != 390 {
+		t.Fatalf("getProducerDowntimeBlocks(2) = %d, %d, %v; expected 300, 390", start, end, err)
+	}
+
+	heimdall.InjectFault("/bor/producers", fakenode.Fault{Malformed: true, Times: 1})
+	if _, _, err := getProducerDowntimeBlocks(2); err == nil || !strings.Contains(err.Error(), "failed to parse response") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestGetBorBlockAuthor(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	chain.AddBlocks(2, producerA)
+	chain.AddBlocks(1, producerB)
+	bor, _ := startFakeNodes(t, chain)
+
+	for block, expected := range map[int64]string{2: producerA, 3: producerB} {
+		author, err := getBorBlockAuthor(block)
+		if err != nil || author != expected {
+			t.Errorf("getBorBlockAuthor(%d) = %s, %v; expected %s", block, author, err, expected)
+		}
+	}
+	if _, err := getBorBlockAuthor(10); err == nil || !strings.Contains(err.Error(), "bor_getAuthor error") {
+		t.Errorf("expected a bor_getAuthor error for a future block, got %v", err)
+	}
+
+	bor.InjectFault("bor_getAuthor", fakenode.Fault{Status: http.StatusBadGateway, Times: 1})
+	if _, err := getBorBlockAuthor(2); err == nil || !strings.Contains(err.Error(), "unexpected status 502") {
+		t.Errorf("expected an unexpected status error, got %v", err)
+	}
+}
+
+func TestWaitForBlock(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	bor, _ := startFakeNodes(t, chain)
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			time.Sleep(10 * time.Millisecond)
+			chain.AddBlocks(1, producerA)
+		}
+	}()
+	if err := waitForBlock(5, 5*time.Millisecond); err != nil {
+		t.Fatalf("waitForBlock: %v", err)
+	}
+	if number, err := getCurrentBorBlockNumber(); err != nil || number < 5 {
+		t.Errorf("getCurrentBorBlockNumber() = %d, %v; expected at least 5", number, err)
+	}
+
+	bor.InjectFault("eth_getBlockByNumber", fakenode.Fault{Malformed: true})
+	if err := waitForBlock(10, time.Millisecond); err == nil || !strings.Contains(err.Error(), "failed to parse block response") {
+		t.Errorf("expected waitForBlock to fail on a malformed answer, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A cassette is a directory holding the calls of a run recorded with --record: a manifest
+// with the seed of the run, and the request and response bodies of every call, one JSON
+// object per line, appended as they happen so that a run exiting on failure keeps them.
+const (
+	cassetteManifestFileName     = "cassette.json"
+	cassetteInteractionsFileName = "interactions.jsonl"
+)
+
+// clock returns the time block timestamps are checked against. Replays move it back to
+// when the cassette was recorded.
+var clock = time.Now
+
+type cassetteManifest struct {
+	Seed       int64     `json:"seed"`
+	RecordedAt time.Time `json:"recordedAt"`
+	RPCURL     string    `json:"rpcUrl"`
+}
+
+// cassetteInteraction is a call as sent and answered. Request is kept as a string since
+// raw requests may not be valid JSON.
+type cassetteInteraction struct {
+	Request  string          `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// recordingClient saves every successful call of the client it wraps to a cassette.
+type recordingClient struct {
+	RPCClient
+	mu   sync.Mutex
+	file *os.File
+}
+
+// recordingRawClient also records the raw requests of the clients able to send them.
+type recordingRawClient struct {
+	*recordingClient
+	rawCaller RawCaller
+}
+
+func newRecordingClient(client RPCClient, dir string, manifest cassetteManifest) (RPCClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating cassette directory: %w", err)
+	}
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, cassetteManifestFileName), manifestBytes, 0644); err != nil {
+		return nil, fmt.Errorf("error writing cassette manifest: %w", err)
+	}
+	file, err := os.Create(filepath.Join(dir, cassetteInteractionsFileName))
+	if err != nil {
+		return nil, fmt.Errorf("error creating cassette: %w", err)
+	}
+
+	recorder := &recordingClient{RPCClient: client, file: file}
+	if rawCaller, ok := client.(RawCaller); ok {
+		return &recordingRawClient{recordingClient: recorder, rawCaller: rawCaller}, nil
+	}
+	return recorder, nil
+}
+
+func (c *recordingClient) Call(requests []Request) ([]Response, error) {
+	responses, err := c.RPCClient.Call(requests)
+	if err != nil {
+		return responses, err
+	}
+	// Marshalled the way the clients do, so the replay matches the bodies they send
+	request, err := json.Marshal(requests)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request: %w", err)
+	}
+	return responses, c.record(string(request), responses)
+}
+
+func (c *recordingRawClient) CallRaw(body []byte) (*Response, error) {
+	response, err := c.rawCaller.CallRaw(body)
+	if err != nil {
+		return nil, err
+	}
+	return response, c.record(string(body), response)
+}
+
+func (c *recordingClient) record(request string, response interface{}) error {
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("error marshalling response: %w", err)
+	}
+	line, err := json.Marshal(cassetteInteraction{Request: request, Response: responseBytes})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error recording call: %w", err)
+	}
+	return nil
+}
+
+func (c *recordingClient) Close() error {
+	return errors.Join(c.file.Close(), c.RPCClient.Close())
+}
+
+// replayServer is an HTTP JSON-RPC endpoint answering the calls recorded in a cassette.
+// Identical requests, like the ones re-issued by --repeat, get their recorded responses
+// in order, the last one being served again once they are exhausted.
+type replayServer struct {
+	URL      string
+	Manifest cassetteManifest
+
+	listener  net.Listener
+	mu        sync.Mutex
+	responses map[string][]json.RawMessage
+}
+
+func newReplayServer(dir string) (*replayServer, error) {
+	manifestBytes, err := os.ReadFile(filepath.Join(dir, cassetteManifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette manifest: %w", err)
+	}
+	server := &replayServer{responses: make(map[string][]json.RawMessage)}
+	if err := json.Unmarshal(manifestBytes, &server.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid cassette manifest: %w", err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, cassetteInteractionsFileName))
+	if err != nil {
+		return nil, fmt.Errorf("error opening cassette: %w", err)
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		lineBytes, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(lineBytes)) > 0 {
+			var interaction cassetteInteraction
+			if err := json.Unmarshal(lineBytes, &interaction); err != nil {
+				return nil, fmt.Errorf("invalid interaction on line %d of the cassette: %w", line, err)
+			}
+			server.responses[interaction.Request] = append(server.responses[interaction.Request], interaction.Response)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading cassette: %w", err)
+		}
+	}
+
+	server.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error listening for replayed calls: %w", err)
+	}
+	server.URL = "http://" + server.listener.Addr().String()
+	go http.Serve(server.listener, server)
+	return server, nil
+}
+
+func (s *replayServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	responses := s.responses[string(body)]
+	var response json.RawMessage
+	if len(responses) > 0 {
+		response = responses[0]
+		if len(responses) > 1 {
+			s.responses[string(body)] = responses[1:]
+		}
+	}
+	s.mu.Unlock()
+
+	if response == nil {
+		http.Error(w, fmt.Sprintf("no recorded response for request %s", body), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// replayClock returns a clock starting at the time the cassette was recorded.
+func (s *replayServer) replayClock() func() time.Time {
+	offset := time.Since(s.Manifest.RecordedAt)
+	return func() time.Time {
+		return time.Now().Add(-offset)
+	}
+}
+
+func (s *replayServer) Close() error {
+	return s.listener.Close()
+}
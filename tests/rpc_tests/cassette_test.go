@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"fakenode"
+)
+
+func TestCassetteRoundTrip(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	chain.AddBlocks(5, "0x00000000000000000000000000000000000000aa")
+	bor := fakenode.NewBor(chain)
+	defer bor.Close()
+
+	dir := t.TempDir()
+	manifest := cassetteManifest{Seed: 42, RecordedAt: time.Now().Add(-time.Hour).UTC().Truncate(time.Second), RPCURL: bor.URL}
+	recorder, err := newRecordingClient(newTestHTTPClient(bor.URL), dir, manifest)
+	if err != nil {
+		t.Fatalf("newRecordingClient: %v", err)
+	}
+
+	blockNumber := []Request{{JsonRPC: "2.0", Method: "eth_blockNumber", Params: []interface{}{}, ID: 1}}
+	batch := []Request{
+		{JsonRPC: "2.0", Method: "eth_chainId", Params: []interface{}{}, ID: 2},
+		{JsonRPC: "2.0", Method: "eth_getBlockByNumber", Params: []interface{}{"0x3", false}, ID: 3},
+	}
+	raw := []byte(`{"jsonrpc":"2.0","method":"eth_chainId","params":[],"id":4`)
+	call := func(client RPCClient) [][]Response {
+		t.Helper()
+		var calls [][]Response
+		// The same request is answered differently once the chain moves on
+		for _, requests := range [][]Request{blockNumber, batch, blockNumber} {
+			responses, err := client.Call(requests)
+			if err != nil {
+				t.Fatalf("%s: %v", requests[0].Method, err)
+			}
+			calls = append(calls, responses)
+			if client == recorder {
+				chain.AddBlocks(1, "0x00000000000000000000000000000000000000aa")
+			}
+		}
+		response, err := client.(RawCaller).CallRaw(raw)
+		if err != nil {
+			t.Fatalf("raw request: %v", err)
+		}
+		return append(calls, []Response{*response})
+	}
+	recorded := call(recorder)
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if formatValue(recorded[0]) == formatValue(recorded[2]) {
+		t.Fatal("expected eth_blockNumber to change while recording")
+	}
+
+	server, err := newReplayServer(dir)
+	if err != nil {
+		t.Fatalf("newReplayServer: %v", err)
+	}
+	defer server.Close()
+	if !reflect.DeepEqual(server.Manifest, manifest) {
+		t.Errorf("expected manifest %+v, got %+v", manifest, server.Manifest)
+	}
+	replayer := newTestHTTPClient(server.URL)
+	if replayed := call(replayer); formatValue(replayed) != formatValue(recorded) {
+		t.Errorf("expected the recorded responses\n%s\ngot\n%s", formatValue(recorded), formatValue(replayed))
+	}
+
+	// Once its responses are exhausted, a request gets the last one again
+	responses, err := replayer.Call(blockNumber)
+	if err != nil || formatValue(responses) != formatValue(recorded[2]) {
+		t.Errorf("expected the last eth_blockNumber response again, got %v (%v)", responses, err)
+	}
+	if _, err := replayer.Call([]Request{{JsonRPC: "2.0", Method: "eth_gasPrice", Params: []interface{}{}, ID: 5}}); err == nil {
+		t.Error("expected an error for a request that was not recorded")
+	}
+
+	if now := server.replayClock()(); now.Sub(manifest.RecordedAt).Abs() > time.Minute {
+		t.Errorf("expected the replay clock to start when the cassette was recorded, at %s, got %s", manifest.RecordedAt, now)
+	}
+	if _, err := newReplayServer(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without cassette")
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// headerBatchSize is the number of eth_getHeaderByNumber requests sent per call, below
+// the default batch limit of Bor.
+const headerBatchSize = 100
+
+// fetchHeaders returns the headers of the blocks start to end included, checking that
+// they hash correctly and form a chain.
+func fetchHeaders(client RPCClient, start, end uint64) ([]*RPCHeader, error) {
+	if start > end {
+		return nil, fmt.Errorf("invalid block range %d-%d", start, end)
+	}
+	headers := make([]*RPCHeader, 0, end-start+1)
+	for from := start; from <= end; from += headerBatchSize {
+		to := min(from+headerBatchSize-1, end)
+		requests := make([]Request, 0, to-from+1)
+		indexes := make(map[int]int)
+		for number := from; number <= to; number++ {
+			request := NewRequest("eth_getHeaderByNumber", []interface{}{fmt.Sprintf("0x%x", number)})
+			indexes[request.ID] = int(number - from)
+			requests = append(requests, *request)
+		}
+		responses, err := client.Call(requests)
+		if err != nil {
+			return nil, err
+		}
+		if len(responses) != len(requests) {
+			return nil, fmt.Errorf("expected %d responses, got %d", len(requests), len(responses))
+		}
+
+		batch := make([]*RPCHeader, len(requests))
+		for _, response := range responses {
+			i, ok := indexes[response.ID]
+			if !ok {
+				return nil, fmt.Errorf("unexpected response id %d", response.ID)
+			}
+			if response.Error != nil {
+				return nil, fmt.Errorf("request error; message: %s | code: %d", response.Error.Message, response.Error.Code)
+			}
+			header, err := parseObject[RPCHeader](response.Result)
+			if err != nil {
+				return nil, fmt.Errorf("header %d: %w", from+uint64(i), err)
+			}
+			if header == nil {
+				return nil, fmt.Errorf("header %d not found", from+uint64(i))
+			}
+			if err := verifyHeaderHash(header); err != nil {
+				return nil, err
+			}
+			batch[i] = header
+		}
+		headers = append(headers, batch...)
+	}
+
+	for i, header := range headers {
+		if header.Number.ToInt().Uint64() != start+uint64(i) {
+			return nil, fmt.Errorf("requested header %d, got %s", start+uint64(i), header.Number)
+		}
+		if i > 0 && *header.ParentHash != *headers[i-1].Hash {
+			return nil, fmt.Errorf("header %s does not follow %s", header.Number, headers[i-1].Number)
+		}
+	}
+	return headers, nil
+}
+
+// checkpointRootHash computes the root of the Merkle tree Heimdall checkpoints. Each
+// leaf commits to the number, timestamp, transactionsRoot and receiptsRoot of a block,
+// and the leaves are padded with zero hashes up to a power of two.
+func checkpointRootHash(headers []*RPCHeader) (common.Hash, error) {
+	leaves := make([]common.Hash, 1)
+	for len(leaves) < len(headers) {
+		leaves = make([]common.Hash, 2*len(leaves))
+	}
+	for i, header := range headers {
+		if header.Number == nil || header.Timestamp == nil || header.TransactionsRoot == nil || header.ReceiptsRoot == nil {
+			return common.Hash{}, fmt.Errorf("header %d is missing fields of the checkpoint leaf", i)
+		}
+		leaves[i] = crypto.Keccak256Hash(
+			common.LeftPadBytes(header.Number.ToInt().Bytes(), common.HashLength),
+			common.LeftPadBytes(new(big.Int).SetUint64(uint64(*header.Timestamp)).Bytes(), common.HashLength),
+			header.TransactionsRoot.Bytes(),
+			header.ReceiptsRoot.Bytes(),
+		)
+	}
+
+	for len(leaves) > 1 {
+		parents := make([]common.Hash, len(leaves)/2)
+		for i := range parents {
+			parents[i] = crypto.Keccak256Hash(leaves[2*i].Bytes(), leaves[2*i+1].Bytes())
+		}
+		leaves = parents
+	}
+	return leaves[0], nil
+}
+
+// verifyRootHash checks a bor_getRootHash result against the root computed from the
+// headers of the blocks start to end.
+func verifyRootHash(client RPCClient, start, end uint64, rootHash string) error {
+	returned, err := hex.DecodeString(rootHash)
+	if err != nil || len(returned) != common.HashLength {
+		return fmt.Errorf("invalid root hash %q", rootHash)
+	}
+	headers, err := fetchHeaders(client, start, end)
+	if err != nil {
+		return fmt.Errorf("error fetching headers %d-%d: %w", start, end, err)
+	}
+	computed, err := checkpointRootHash(headers)
+	if err != nil {
+		return err
+	}
+	if computed != common.BytesToHash(returned) {
+		return fmt.Errorf("invalid root hash of blocks %d-%d: computed %x, returned %s", start, end, computed, rootHash)
+	}
+	return nil
+}
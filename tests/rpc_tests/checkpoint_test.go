@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"fakenode"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testHeaderChain returns count chained headers starting at block 1.
+func testHeaderChain(count int) []*types.Header {
+	var headers []*types.Header
+	parent := common.Hash{}
+	for i := 1; i <= count; i++ {
+		header := &types.Header{
+			ParentHash:  parent,
+			UncleHash:   types.EmptyUncleHash,
+			TxHash:      crypto.Keccak256Hash([]byte(fmt.Sprintf("transactions %d", i))),
+			ReceiptHash: crypto.Keccak256Hash([]byte(fmt.Sprintf("receipts %d", i))),
+			Difficulty:  big.NewInt(1),
+			Number:      big.NewInt(int64(i)),
+			GasLimit:    30_000_000,
+			Time:        uint64(1_700_000_000 + 2*i),
+			BaseFee:     big.NewInt(7),
+		}
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	return headers
+}
+
+// checkpointLeaf is the leaf Heimdall commits to for a header.
+func checkpointLeaf(header *types.Header) common.Hash {
+	return crypto.Keccak256Hash(
+		common.BigToHash(header.Number).Bytes(),
+		common.BigToHash(new(big.Int).SetUint64(header.Time)).Bytes(),
+		header.TxHash.Bytes(),
+		header.ReceiptHash.Bytes(),
+	)
+}
+
+func TestCheckpointRootHash(t *testing.T) {
+	chain := testHeaderChain(3)
+	leaves := make([]common.Hash, len(chain))
+	for i, header := range chain {
+		leaves[i] = checkpointLeaf(header)
+	}
+	node := func(left, right common.Hash) common.Hash {
+		return crypto.Keccak256Hash(left.Bytes(), right.Bytes())
+	}
+
+	for _, tt := range []struct {
+		count    int
+		expected common.Hash
+	}{
+		{1, leaves[0]},
+		{2, node(leaves[0], leaves[1])},
+		// Padded with a zero leaf up to 4 leaves
+		{3, node(node(leaves[0], leaves[1]), node(leaves[2], common.Hash{}))},
+	} {
+		var headers []*RPCHeader
+		for _, header := range chain[:tt.count] {
+			headers = append(headers, rpcHeader(t, header))
+		}
+		root, err := checkpointRootHash(headers)
+		if err != nil {
+			t.Fatalf("%d headers: %v", tt.count, err)
+		}
+		if root != tt.expected {
+			t.Errorf("%d headers: expected root %s, got %s", tt.count, tt.expected, root)
+		}
+	}
+
+	header := rpcHeader(t, chain[0])
+	header.ReceiptsRoot = nil
+	if _, err := checkpointRootHash([]*RPCHeader{header}); err == nil {
+		t.Error("expected an error for a header without receiptsRoot")
+	}
+}
+
+func TestVerifyRootHash(t *testing.T) {
+	chain := testHeaderChain(150)
+	bor := fakenode.NewBor(fakenode.NewChain(137))
+	defer bor.Close()
+	bor.Handle("eth_getHeaderByNumber", func(params []json.RawMessage) (interface{}, error) {
+		var number hexutil.Uint64
+		if err := json.Unmarshal(params[0], &number); err != nil {
+			return nil, &fakenode.Error{Code: errCodeInvalidParams, Message: err.Error()}
+		}
+		if number == 0 || int(number) > len(chain) {
+			return nil, nil
+		}
+		return chain[number-1], nil
+	})
+	client := newTestHTTPClient(bor.URL)
+
+	// The range spans two batches of headers
+	var headers []*RPCHeader
+	for _, header := range chain[4:130] {
+		headers = append(headers, rpcHeader(t, header))
+	}
+	root, err := checkpointRootHash(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootHash := strings.TrimPrefix(root.Hex(), "0x")
+
+	for _, tt := range []struct {
+		name       string
+		start, end uint64
+		rootHash   string
+		err        string
+	}{
+		{"valid", 5, 130, rootHash, ""},
+		{"other range", 5, 129, rootHash, "invalid root hash of blocks 5-129"},
+		{"0x prefix", 5, 130, root.Hex(), "invalid root hash"},
+		{"short", 5, 130, rootHash[2:], "invalid root hash"},
+		{"missing header", 140, 151, rootHash, "header 151 not found"},
+		{"inverted range", 10, 5, rootHash, "invalid block range 10-5"},
+	} {
+		err := verifyRootHash(client, tt.start, tt.end, tt.rootHash)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	// Headers that do not follow each other are rejected
+	chain[9] = testHeaderChain(10)[9]
+	chain[9].Extra = []byte("fork")
+	if err := verifyRootHash(client, 5, 130, rootHash); err == nil || !strings.Contains(err.Error(), "does not follow") {
+		t.Errorf("expected an error for a broken chain, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// stateReceiverAddress is the Bor genesis contract executing the state-sync events.
+var stateReceiverAddress = common.HexToAddress("0x0000000000000000000000000000000000001001")
+
+// stateCommittedFilter returns the eth_getLogs filter of the state-sync events committed
+// in the --state-sync-blocks blocks up to toBlock.
+func stateCommittedFilter(toBlock *big.Int) map[string]interface{} {
+	fromBlock := new(big.Int).Sub(toBlock, new(big.Int).SetUint64(*stateSyncBlocks))
+	if fromBlock.Sign() < 0 {
+		fromBlock = big.NewInt(0)
+	}
+	return map[string]interface{}{
+		"address":   stateReceiverAddress.Hex(),
+		"topics":    [][]common.Hash{{stateSyncLogTopic}},
+		"fromBlock": fmt.Sprintf("0x%x", fromBlock),
+		"toBlock":   fmt.Sprintf("0x%x", toBlock),
+	}
+}
+
+// heimdallEventRecord is a clerk event record, an L1 state-sync event seen by Heimdall.
+type heimdallEventRecord struct {
+	ID         heimdallUint64 `json:"id"`
+	Contract   common.Address `json:"contract"`
+	Data       heimdallBytes  `json:"data"`
+	TxHash     common.Hash    `json:"tx_hash"`
+	LogIndex   heimdallUint64 `json:"log_index"`
+	BorChainID string         `json:"bor_chain_id"`
+}
+
+func (c *heimdallClient) eventRecord(stateID uint64) (*heimdallEventRecord, error) {
+	var response struct {
+		Record *heimdallEventRecord `json:"record"`
+	}
+	if err := c.get(fmt.Sprintf("/clerk/event-records/%d", stateID), &response); err != nil {
+		return nil, err
+	}
+	if response.Record == nil {
+		return nil, fmt.Errorf("no event record %d in Heimdall response", stateID)
+	}
+	return response.Record, nil
+}
+
+// errStateSyncDataNotExposed is reported for the state-sync events whose record cannot be
+// compared with Heimdall's, since the node does not list the records it executed.
+var errStateSyncDataNotExposed = errors.New("not verifiable: stateSyncData not exposed")
+
+// borStateSyncData is an event record executed by Bor. Since PIP-74 the state-sync
+// transaction lists the records it executes; older nodes do not expose them.
+type borStateSyncData struct {
+	ID       uint64         `json:"id"`
+	Contract common.Address `json:"contract"`
+	Data     string         `json:"data"`
+	TxHash   common.Hash    `json:"txHash"`
+}
+
+// StateSyncEventCheck is the outcome of cross-checking a state-sync event committed by Bor
+// with its clerk event record in Heimdall.
+type StateSyncEventCheck struct {
+	StateID     uint64
+	BlockNumber uint64
+	TxHash      common.Hash
+	Success     bool
+	Contract    common.Address
+	L1TxHash    common.Hash
+	// Verified is set when the contract, data and L1 tx hash of the record were compared
+	// with the ones executed by Bor and match.
+	Verified bool
+	Err      error
+}
+
+// checkStateSyncEvents fetches the clerk event record of every StateCommitted log and
+// compares it with the record executed by Bor. The events of nodes not exposing the
+// executed records fail with errStateSyncDataNotExposed.
+func checkStateSyncEvents(client RPCClient, heimdall *heimdallClient, chainID *big.Int, logs []types.Log) []StateSyncEventCheck {
+	executedByTx := make(map[common.Hash]map[uint64]borStateSyncData)
+	checks := make([]StateSyncEventCheck, 0, len(logs))
+	for _, logEvent := range logs {
+		check := StateSyncEventCheck{BlockNumber: logEvent.BlockNumber, TxHash: logEvent.TxHash}
+		if len(logEvent.Topics) != 2 || len(logEvent.Data) != common.HashLength {
+			check.Err = fmt.Errorf("invalid StateCommitted log %d in block %d", logEvent.Index, logEvent.BlockNumber)
+			checks = append(checks, check)
+			continue
+		}
+		check.StateID = logEvent.Topics[1].Big().Uint64()
+		check.Success = new(big.Int).SetBytes(logEvent.Data).Sign() != 0
+
+		executed, ok := executedByTx[logEvent.TxHash]
+		if !ok {
+			var err error
+			executed, err = fetchExecutedStateSyncs(client, logEvent.TxHash)
+			if err != nil {
+				check.Err = fmt.Errorf("error fetching state-sync transaction %s: %w", logEvent.TxHash, err)
+				checks = append(checks, check)
+				continue
+			}
+			executedByTx[logEvent.TxHash] = executed
+		}
+
+		record, err := heimdall.eventRecord(check.StateID)
+		if err != nil {
+			check.Err = err
+			checks = append(checks, check)
+			continue
+		}
+		check.Contract = record.Contract
+		check.L1TxHash = record.TxHash
+		check.Err = compareEventRecord(check.StateID, record, chainID, executed)
+		check.Verified = check.Err == nil
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// fetchExecutedStateSyncs returns the records executed by a state-sync transaction by
+// state ID, or nil when the node does not list them.
+func fetchExecutedStateSyncs(client RPCClient, txHash common.Hash) (map[uint64]borStateSyncData, error) {
+	responses, err := client.Call([]Request{*NewRequest("eth_getTransactionByHash", []interface{}{txHash})})
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) != 1 {
+		return nil, fmt.Errorf("expected 1 response, got %d", len(responses))
+	}
+	if responses[0].Error != nil {
+		return nil, fmt.Errorf("request error; message: %s | code: %d", responses[0].Error.Message, responses[0].Error.Code)
+	}
+	tx, err := parseResponse[struct {
+		StateSyncData []borStateSyncData `json:"stateSyncData"`
+	}](responses[0].Result)
+	if err != nil {
+		return nil, err
+	}
+	if tx.StateSyncData == nil {
+		return nil, nil
+	}
+	executed := make(map[uint64]borStateSyncData, len(tx.StateSyncData))
+	for _, data := range tx.StateSyncData {
+		executed[data.ID] = data
+	}
+	return executed, nil
+}
+
+// compareEventRecord checks a clerk event record against the state-sync event committed
+// by Bor. executed is nil when the node does not expose the records it executed, in
+// which case the record cannot be verified.
+func compareEventRecord(stateID uint64, record *heimdallEventRecord, chainID *big.Int, executed map[uint64]borStateSyncData) error {
+	if uint64(record.ID) != stateID {
+		return fmt.Errorf("requested event record %d, got %d", stateID, record.ID)
+	}
+	if chainID != nil && record.BorChainID != chainID.String() {
+		return fmt.Errorf("event record %d is for chain %s, not %s", stateID, record.BorChainID, chainID)
+	}
+	if executed == nil {
+		return fmt.Errorf("state %d: %w", stateID, errStateSyncDataNotExposed)
+	}
+
+	data, ok := executed[stateID]
+	if !ok {
+		return fmt.Errorf("state %d is committed but not listed in its state-sync transaction", stateID)
+	}
+	if data.Contract != record.Contract {
+		return fmt.Errorf("invalid contract of state %d: Heimdall has %s, Bor executed %s", stateID, record.Contract, data.Contract)
+	}
+	executedData, err := hex.DecodeString(strings.TrimPrefix(data.Data, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid data of state %d executed by Bor: %w", stateID, err)
+	}
+	if !bytes.Equal(executedData, record.Data) {
+		return fmt.Errorf("invalid data of state %d: Heimdall has 0x%x, Bor executed 0x%x", stateID, []byte(record.Data), executedData)
+	}
+	if data.TxHash != record.TxHash {
+		return fmt.Errorf("invalid L1 tx hash of state %d: Heimdall has %s, Bor executed %s", stateID, record.TxHash, data.TxHash)
+	}
+	return nil
+}
+
+func printStateSyncEvents(checks []StateSyncEventCheck) {
+	fmt.Printf("\n🔁  State-Sync Events:\n")
+	for _, check := range checks {
+		switch {
+		case errors.Is(check.Err, errStateSyncDataNotExposed):
+			fmt.Printf("  ⚠️  state %d in block %d: found in Heimdall, not verifiable since the node does not expose the executed record\n", check.StateID, check.BlockNumber)
+		case check.Err != nil:
+			fmt.Printf("  ❌ state %d in block %d: %v\n", check.StateID, check.BlockNumber, check.Err)
+		default:
+			fmt.Printf("  ✅ state %d in block %d: contract %s, L1 tx %s, success %t\n", check.StateID, check.BlockNumber, check.Contract, check.L1TxHash, check.Success)
+		}
+	}
+}
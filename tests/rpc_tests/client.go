@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// subscriptionNotificationTimeout bounds how long subscription test cases wait for a notification.
+	subscriptionNotificationTimeout = 60 * time.Second
+)
+
+// RPCClient sends JSON-RPC batches to a node over a given transport.
+type RPCClient interface {
+	Call(requests []Request) ([]Response, error)
+	Close() error
+}
+
+// RawCaller is implemented by the clients able to send a request body as is, which
+// negative test cases use to send malformed JSON.
+type RawCaller interface {
+	CallRaw(body []byte) (*Response, error)
+}
+
+// Subscriber gives access to the notifications received for eth_subscribe subscriptions.
+type Subscriber interface {
+	Notifications(subscriptionID string) <-chan json.RawMessage
+}
+
+// clientConfig controls how long a call may take and how failed calls are retried.
+type clientConfig struct {
+	// Timeout bounds a single attempt, from sending the batch to reading all responses.
+	Timeout time.Duration
+	// MaxRetries is the number of extra attempts made for a batch that failed with a
+	// retryable error. Batches containing state changing methods are never retried.
+	MaxRetries int
+	// Backoff is the delay before the first retry; it doubles on every retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// backoff returns the delay to wait before the given retry, starting at 1.
+func (c clientConfig) backoff(retry int) time.Duration {
+	delay := c.Backoff
+	for i := 1; i < retry && delay < c.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > c.MaxBackoff {
+		delay = c.MaxBackoff
+	}
+	return delay
+}
+
+// newRPCClient returns a WebSocket client for ws:// and wss:// urls and an HTTP client otherwise.
+func newRPCClient(url string, config clientConfig) (RPCClient, error) {
+	if isWebSocketURL(url) {
+		return dialWebSocket(url, config)
+	}
+	return &httpClient{
+		url:    url,
+		client: &http.Client{Timeout: config.Timeout},
+		config: config,
+	}, nil
+}
+
+func isWebSocketURL(url string) bool {
+	return strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://")
+}
+
+type httpClient struct {
+	url    string
+	client *http.Client
+	config clientConfig
+}
+
+// httpStatusError is returned when the node answers with a non 200 HTTP status.
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d: %s", e.StatusCode, e.Body)
+}
+
+// Call sends the batch, retrying with exponential backoff when the node could not be
+// reached or answered with a transient HTTP status and every method in the batch is
+// safe to send again.
+func (c *httpClient) Call(requests []Request) ([]Response, error) {
+	retryable := isIdempotentBatch(requests)
+	for attempt := 0; ; attempt++ {
+		responses, err := CallEthereumRPC(c.client, requests, c.url)
+		if err == nil {
+			return responses, nil
+		}
+		if !retryable || attempt >= c.config.MaxRetries || !isRetryableError(err) {
+			if attempt > 0 {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+			}
+			return nil, err
+		}
+
+		delay := c.config.backoff(attempt + 1)
+		fmt.Printf("⚠️  RPC call to %s failed (%v), retrying in %s (%d/%d)\n", c.url, err, delay, attempt+1, c.config.MaxRetries)
+		time.Sleep(delay)
+	}
+}
+
+// isIdempotentBatch reports whether every request of the batch can be sent again
+// without changing the outcome of the run.
+func isIdempotentBatch(requests []Request) bool {
+	for _, request := range requests {
+		if !isReadOnlyMethod(request.Method) {
+			return false
+		}
+	}
+	return true
+}
+
+// isRetryableError reports whether err is a transport failure or a transient HTTP
+// status, as opposed to a malformed response that would fail again.
+func isRetryableError(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var transportErr *transportError
+	return errors.As(err, &transportErr)
+}
+
+// CallRaw posts body once, without retrying, and decodes the single response it gets.
+func (c *httpClient) CallRaw(body []byte) (*Response, error) {
+	answer, err := postJSON(c.client, body, c.url)
+	if err != nil {
+		return nil, err
+	}
+	var response Response
+	if err := json.Unmarshal(answer, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response: %w", err)
+	}
+	return &response, nil
+}
+
+func (c *httpClient) Close() error {
+	return nil
+}
+
+type wsClient struct {
+	conn    *websocket.Conn
+	config  clientConfig
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	pending       map[int]chan Response
+	unexpected    []Response
+	subscriptions map[string]chan json.RawMessage
+	closed        chan struct{}
+	readErr       error
+}
+
+// subscriptionNotification is the message a node pushes for every eth_subscribe event.
+type subscriptionNotification struct {
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+func dialWebSocket(url string, config clientConfig) (*wsClient, error) {
+	dialer := *websocket.DefaultDialer
+	dialer.HandshakeTimeout = config.Timeout
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing WebSocket: %w", err)
+	}
+
+	c := &wsClient{
+		conn:          conn,
+		config:        config,
+		pending:       make(map[int]chan Response),
+		subscriptions: make(map[string]chan json.RawMessage),
+		closed:        make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *wsClient) Call(requests []Request) ([]Response, error) {
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	// Channels are registered before writing so no response can be missed
+	responseChannels := make([]chan Response, len(requests))
+	c.mu.Lock()
+	for i, request := range requests {
+		responseChannels[i] = make(chan Response, 1)
+		c.pending[request.ID] = responseChannels[i]
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		for _, request := range requests {
+			delete(c.pending, request.ID)
+		}
+		c.mu.Unlock()
+	}()
+
+	reqBytes, err := json.Marshal(requests)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request: %w", err)
+	}
+
+	c.writeMu.Lock()
+	err = c.conn.WriteMessage(websocket.TextMessage, reqBytes)
+	c.writeMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("error making RPC call: %w", err)
+	}
+
+	timeout := time.NewTimer(c.config.Timeout)
+	defer timeout.Stop()
+
+	responses := make([]Response, 0, len(requests))
+	for _, responseChannel := range responseChannels {
+		select {
+		case response := <-responseChannel:
+			responses = append(responses, response)
+		case <-c.closed:
+			return c.withUnexpected(responses), fmt.Errorf("WebSocket connection closed: %w", c.readErr)
+		case <-timeout.C:
+			return c.withUnexpected(responses), fmt.Errorf("timed out after %s waiting for %d response(s)", c.config.Timeout, len(requests)-len(responses))
+		}
+	}
+
+	return c.withUnexpected(responses), nil
+}
+
+// withUnexpected appends to responses the ones received so far that no call waited for,
+// so that the caller reports them.
+func (c *wsClient) withUnexpected(responses []Response) []Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	responses = append(responses, c.unexpected...)
+	c.unexpected = nil
+	return responses
+}
+
+// Notifications returns the channel receiving the results pushed for a subscription.
+// Notifications arriving before the first call are buffered.
+func (c *wsClient) Notifications(subscriptionID string) <-chan json.RawMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscriptionChannel(subscriptionID)
+}
+
+// subscriptionChannel must be called with c.mu held.
+func (c *wsClient) subscriptionChannel(subscriptionID string) chan json.RawMessage {
+	ch, ok := c.subscriptions[subscriptionID]
+	if !ok {
+		ch = make(chan json.RawMessage, 1024)
+		c.subscriptions[subscriptionID] = ch
+	}
+	return ch
+}
+
+func (c *wsClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *wsClient) readLoop() {
+	defer close(c.closed)
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			c.readErr = err
+			return
+		}
+
+		message = bytes.TrimSpace(message)
+		if len(message) == 0 {
+			continue
+		}
+
+		if message[0] == '[' {
+			var responses []Response
+			if err := json.Unmarshal(message, &responses); err != nil {
+				c.readErr = fmt.Errorf("error unmarshalling response: %w", err)
+				return
+			}
+			c.dispatchResponses(responses)
+			continue
+		}
+
+		var notification subscriptionNotification
+		if err := json.Unmarshal(message, &notification); err == nil && notification.Method == "eth_subscription" {
+			c.dispatchNotification(notification)
+			continue
+		}
+
+		var response Response
+		if err := json.Unmarshal(message, &response); err != nil {
+			c.readErr = fmt.Errorf("error unmarshalling response: %w", err)
+			return
+		}
+		c.dispatchResponses([]Response{response})
+	}
+}
+
+// dispatchResponses hands the responses of a message to the calls waiting for them. A
+// call gets a single response per request id; duplicates and unknown ids are kept for
+// the next call to return instead of blocking the read loop. The whole message is
+// dispatched under the lock, so a call sees the duplicates of the responses it received.
+func (c *wsClient) dispatchResponses(responses []Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, response := range responses {
+		if ch, ok := c.pending[response.ID]; ok {
+			delete(c.pending, response.ID)
+			ch <- response
+			continue
+		}
+		c.unexpected = append(c.unexpected, response)
+	}
+}
+
+func (c *wsClient) dispatchNotification(notification subscriptionNotification) {
+	c.mu.Lock()
+	ch := c.subscriptionChannel(notification.Params.Subscription)
+	c.mu.Unlock()
+
+	// Never block the read loop; a full buffer means nobody is consuming this subscription
+	select {
+	case ch <- notification.Params.Result:
+	default:
+	}
+}
+
+// waitForNotification reads notifications of a subscription until match accepts one
+// or the deadline expires.
+func waitForNotification[T any](subscriber Subscriber, subscriptionID string, timeout time.Duration, match func(T) bool) (*T, error) {
+	if subscriber == nil {
+		return nil, errors.New("no WebSocket connection to receive notifications on")
+	}
+
+	notifications := subscriber.Notifications(subscriptionID)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	received := 0
+	for {
+		select {
+		case raw := <-notifications:
+			received++
+			parsed, err := parseResponse[T](raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid notification for subscription %s: %w", subscriptionID, err)
+			}
+			if match(*parsed) {
+				return parsed, nil
+			}
+		case <-deadline.C:
+			return nil, fmt.Errorf("no matching notification for subscription %s within %s (%d received)", subscriptionID, timeout, received)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fakenode"
+	"github.com/gorilla/websocket"
+)
+
+func newTestHTTPClient(url string) RPCClient {
+	client, _ := newRPCClient(url, clientConfig{
+		Timeout:    time.Second,
+		MaxRetries: 2,
+		Backoff:    time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	})
+	return client
+}
+
+func TestCallEthereumRPC(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	chain.AddBlocks(5, "0x00000000000000000000000000000000000000aa")
+	bor := fakenode.NewBor(chain)
+	defer bor.Close()
+
+	requests := []Request{
+		*NewRequest("eth_chainId", []interface{}{}),
+		*NewRequest("eth_blockNumber", []interface{}{}),
+	}
+	responses, err := CallEthereumRPC(&http.Client{Timeout: time.Second}, requests, bor.URL)
+	if err != nil {
+		t.Fatalf("CallEthereumRPC: %v", err)
+	}
+	results := make(map[int]string)
+	for _, response := range responses {
+		results[response.ID] = string(response.Result)
+	}
+	if results[requests[0].ID] != `"0x89"` || results[requests[1].ID] != `"0x5"` {
+		t.Errorf("unexpected results %v", results)
+	}
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	bor := fakenode.NewBor(fakenode.NewChain(137))
+	defer bor.Close()
+	client := newTestHTTPClient(bor.URL)
+
+	// Read-only batches are retried on transient statuses
+	bor.InjectFault("eth_blockNumber", fakenode.Fault{Status: http.StatusServiceUnavailable, Times: 2})
+	if _, err := client.Call([]Request{*NewRequest("eth_blockNumber", []interface{}{})}); err != nil {
+		t.Errorf("expected the call to succeed after retries, got %v", err)
+	}
+
+	// Batches changing the state are never sent twice
+	bor.InjectFault("eth_sendRawTransaction", fakenode.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+	_, err := client.Call([]Request{*NewRequest("eth_sendRawTransaction", []interface{}{"0x00"})})
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected a 503 without retry, got %v", err)
+	}
+
+	// Malformed answers fail again, they are not retried
+	bor.InjectFault("", fakenode.Fault{Malformed: true, Times: 1})
+	_, err = client.Call([]Request{*NewRequest("eth_blockNumber", []interface{}{})})
+	if err == nil || strings.Contains(err.Error(), "giving up") {
+		t.Errorf("expected an unmarshalling error without retry, got %v", err)
+	}
+}
+
+func TestHTTPClientCallRaw(t *testing.T) {
+	bor := fakenode.NewBor(fakenode.NewChain(137))
+	defer bor.Close()
+	rawCaller := newTestHTTPClient(bor.URL).(RawCaller)
+
+	response, err := rawCaller.CallRaw([]byte(`{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1`))
+	if err != nil {
+		t.Fatalf("CallRaw: %v", err)
+	}
+	expected := ExpectedError{Code: -32700, Message: "parse error"}
+	if err := expected.check(response.Error); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebSocketClientUnexpectedResponses(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var requests []Request
+			if err := conn.ReadJSON(&requests); err != nil {
+				return
+			}
+			// Every request is answered twice, along with a response nobody asked for
+			var responses []Response
+			for _, request := range requests {
+				response := Response{JsonRPC: "2.0", ID: request.ID, Result: json.RawMessage(`"0x1"`)}
+				responses = append(responses, response, response)
+			}
+			responses = append(responses, Response{JsonRPC: "2.0", ID: -1, Result: json.RawMessage(`"0x2"`)})
+			if err := conn.WriteJSON(responses); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	client, err := dialWebSocket("ws"+strings.TrimPrefix(server.URL, "http"), clientConfig{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("dialWebSocket: %v", err)
+	}
+	defer client.Close()
+
+	// The connection keeps serving calls once duplicates were received
+	for i := 0; i < 2; i++ {
+		request := NewRequest("eth_blockNumber", []interface{}{})
+		responses, err := client.Call([]Request{*request})
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		ids := make(map[int]int)
+		for _, response := range responses {
+			ids[response.ID]++
+		}
+		if len(responses) != 3 || ids[request.ID] != 2 || ids[-1] != 1 {
+			t.Errorf("call %d: expected the duplicate and unknown responses to be returned, got %+v", i, responses)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// concurrencyTxsPerSender is how many transactions every sender of the concurrency
+	// scenario sends at once, without waiting for the previous ones to be mined. The
+	// first sender also sends the transaction opening the scenario.
+	concurrencyTxsPerSender = 3
+	// concurrencyFunding is how many transfers at twice the gas price every sender of the
+	// concurrency scenario is funded for.
+	concurrencyFunding = concurrencyTxsPerSender + 1
+)
+
+// concurrencyTestCases fund every sender but the test account, then send transactions
+// from all of them at once, each sender over its own calls rather than a shared batch,
+// to load Bor's txpool and block building the way parallel clients do. senders is the
+// number of sender accounts, the test account included, so there is nothing to test
+// with less than 2.
+func concurrencyTestCases(senders int) []TestCase {
+	if senders < 2 {
+		return nil
+	}
+
+	var testCases []TestCase
+	for i := 1; i < senders; i++ {
+		testCases = append(testCases, TestCase{
+			Key:      fmt.Sprintf("Concurrency Scenario: eth_getTransactionCount (sender %d)", i),
+			Produces: []string{"senderNonces"},
+			PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+				return NewRequest("eth_getTransactionCount", []interface{}{rm.nonces.senders[i].addr, "latest"}), nil
+			},
+			HandleResponse: func(rm *ResponseMap, resp Response) error {
+				count, err := parseResponse[hexutil.Uint64](resp.Result)
+				if err != nil {
+					return err
+				}
+				rm.nonces.sync(rm.nonces.senders[i].addr, uint64(*count))
+				return nil
+			},
+		})
+	}
+
+	for i := 1; i < senders; i++ {
+		testCases = append(testCases, TestCase{
+			Key:      fmt.Sprintf("Concurrency Scenario: eth_sendRawTransaction (fund sender %d)", i),
+			Requires: []string{"chainId", "accountNonce", "gasPrice"},
+			Produces: []string{"fundedSenders"},
+			PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+				if rm.concurrencyFundingTxs == nil {
+					rm.concurrencyFundingTxs = make(map[common.Address]*types.Transaction)
+					rm.concurrencyFundingTxsSentAt = make(map[common.Address]time.Time)
+				}
+				sender := rm.nonces.senders[i]
+				value := new(big.Int).Mul(big.NewInt(21000*2*concurrencyFunding), rm.gasPrice)
+				tx := transferTransaction(rm.nextNonce(), sender.addr, value, rm.gasPrice, rm.account, rm.chainId)
+				rm.concurrencyFundingTxs[sender.addr] = tx
+				rm.concurrencyFundingTxsSentAt[sender.addr] = time.Now()
+				return NewRequest("eth_sendRawTransaction", []interface{}{encodeRawTransaction(tx)}), nil
+			},
+			HandleResponse: func(rm *ResponseMap, resp Response) error {
+				txHash, err := parseResponse[common.Hash](resp.Result)
+				if err != nil {
+					return err
+				}
+				sender := rm.nonces.senders[i]
+				tx := rm.concurrencyFundingTxs[sender.addr]
+				if *txHash != tx.Hash() {
+					return fmt.Errorf("invalid tx hash: expected %s, actual %s", tx.Hash(), txHash)
+				}
+				receipt, err := rm.waitForInclusion(*txHash, rm.concurrencyFundingTxsSentAt[sender.addr])
+				if err != nil {
+					return err
+				}
+				if receipt.BlockNumber == nil {
+					return fmt.Errorf("no block number in the receipt of %s", txHash)
+				}
+				if rm.concurrencyFundedBlock == nil || receipt.BlockNumber.ToInt().Cmp(rm.concurrencyFundedBlock) > 0 {
+					rm.concurrencyFundedBlock = receipt.BlockNumber.ToInt()
+				}
+				return nil
+			},
+		})
+	}
+
+	testCases = append(testCases, TestCase{
+		Key:      "Concurrency Scenario: eth_sendRawTransaction (concurrent senders)",
+		Requires: []string{"senderNonces", "fundedSenders", "chainId", "gasPrice"},
+		Produces: []string{"concurrentTxs"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			first := rm.nonces.senders[1]
+			rm.concurrencyFirstTx = transferTransaction(rm.nonces.reserve(first.addr), first.addr, big.NewInt(0), rm.gasPrice, first, rm.chainId)
+			rm.concurrencyFirstTxSentAt = time.Now()
+			return NewRequest("eth_sendRawTransaction", []interface{}{encodeRawTransaction(rm.concurrencyFirstTx)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			txHash, err := parseResponse[common.Hash](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *txHash != rm.concurrencyFirstTx.Hash() {
+				return fmt.Errorf("invalid tx hash: expected %s, actual %s", rm.concurrencyFirstTx.Hash(), txHash)
+			}
+
+			sent, err := rm.nonces.sendConcurrently(rm.client, rm.nonces.senders[1:], concurrencyTxsPerSender, func(from Account, nonce uint64) *types.Transaction {
+				return transferTransaction(nonce, from.addr, big.NewInt(0), rm.gasPrice, from, rm.chainId)
+			})
+			first := rm.nonces.senders[1].addr
+			sent[first] = append([]*types.Transaction{rm.concurrencyFirstTx}, sent[first]...)
+			rm.concurrentTxs = sent
+			if err != nil {
+				return err
+			}
+
+			inclusions, err := waitForConcurrentTxs(rm, sent, rm.concurrencyFirstTxSentAt)
+			rm.inclusions = append(rm.inclusions, inclusions...)
+			if err != nil {
+				return err
+			}
+			for _, inclusion := range inclusions {
+				if inclusion.BlockNumber.Cmp(rm.concurrencyFundedBlock) <= 0 {
+					return fmt.Errorf("transaction %s mined in block %s, not after its sender was funded in block %s", inclusion.TxHash, inclusion.BlockNumber, rm.concurrencyFundedBlock)
+				}
+			}
+			return nil
+		},
+	})
+
+	for i := 1; i < senders; i++ {
+		testCases = append(testCases, TestCase{
+			Key:      fmt.Sprintf("Concurrency Scenario: eth_getTransactionCount (sender %d, confirmed)", i),
+			Requires: []string{"concurrentTxs"},
+			PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+				return NewRequest("eth_getTransactionCount", []interface{}{rm.nonces.senders[i].addr, "latest"}), nil
+			},
+			HandleResponse: func(rm *ResponseMap, resp Response) error {
+				count, err := parseResponse[hexutil.Uint64](resp.Result)
+				if err != nil {
+					return err
+				}
+				addr := rm.nonces.senders[i].addr
+				expected := concurrencyTxsPerSender
+				if i == 1 {
+					expected++
+				}
+				if len(rm.concurrentTxs[addr]) != expected {
+					return fmt.Errorf("expected %d transactions sent by %s, got %d", expected, addr, len(rm.concurrentTxs[addr]))
+				}
+				if confirmed := rm.nonces.confirmed(addr); uint64(*count) != confirmed {
+					return fmt.Errorf("invalid nonce of %s: expected %d confirmed, the node counts %d", addr, confirmed, uint64(*count))
+				}
+				return nil
+			},
+		})
+	}
+	return testCases
+}
+
+// waitForConcurrentTxs waits for every transaction sent by the concurrency scenario to
+// be mined, confirming the nonces of their sender.
+func waitForConcurrentTxs(rm *ResponseMap, sent map[common.Address][]*types.Transaction, sentAt time.Time) ([]TransactionInclusion, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var inclusions []TransactionInclusion
+	var errs []error
+	for addr, txs := range sent {
+		for _, tx := range txs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, inclusion, err := waitForReceipt(rm.client, tx.Hash(), sentAt, inclusionTimeout, inclusionPollInterval)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+					return
+				}
+				inclusions = append(inclusions, *inclusion)
+				rm.nonces.confirm(addr, tx.Nonce())
+			}()
+		}
+	}
+	wg.Wait()
+	sort.Slice(inclusions, func(i, j int) bool {
+		if c := inclusions[i].BlockNumber.Cmp(inclusions[j].BlockNumber); c != 0 {
+			return c < 0
+		}
+		return inclusions[i].TxHash.Cmp(inclusions[j].TxHash) < 0
+	})
+	return inclusions, errors.Join(errs...)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"fakenode"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestConcurrencyTestCases(t *testing.T) {
+	chainID := big.NewInt(137)
+	pool := newFakeTxPool(chainID)
+	bor := fakenode.NewBor(fakenode.NewChain(chainID.Uint64()))
+	defer bor.Close()
+	bor.Handle("eth_sendRawTransaction", pool.sendRawTransaction)
+	bor.Handle("eth_getTransactionCount", pool.getTransactionCount)
+	bor.Handle("eth_getTransactionReceipt", pool.getTransactionReceipt)
+	client := newTestHTTPClient(bor.URL)
+
+	keys := newRunRandomness(1)
+	var senders Accounts
+	for i := 0; i < 3; i++ {
+		key, err := keys.generateKey()
+		if err != nil {
+			t.Fatalf("generateKey: %v", err)
+		}
+		senders = append(senders, Account{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)})
+	}
+	// The second sender already sent a transaction the nonce manager does not know about
+	pool.nonces[senders[1].addr] = 1
+
+	gasPrice := big.NewInt(30_000_000_000)
+	setup := TestCase{
+		Key:      "setup",
+		Produces: []string{"chainId", "accountNonce", "gasPrice"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_chainId", []interface{}{}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			rm.chainId = chainID
+			rm.gasPrice = gasPrice
+			return nil
+		},
+	}
+	batches, err := scheduleTestCases(append([]TestCase{setup}, concurrencyTestCases(len(senders))...))
+	if err != nil {
+		t.Fatalf("scheduleTestCases: %v", err)
+	}
+	rm := &ResponseMap{client: client, account: senders[0], nonces: newNonceManager(senders)}
+	results, _ := runTestCases(client, batches, rm)
+	for _, result := range results {
+		if result.Status != TestStatusPassed {
+			t.Errorf("%s: expected %s, got %s (%v)", result.Key, TestStatusPassed, result.Status, result.Err)
+		}
+	}
+
+	// Both senders are funded by the test account before sending anything
+	funding := new(big.Int).Mul(big.NewInt(21000*2*concurrencyFunding), gasPrice)
+	funded := make(map[int]bool)
+	for block, tx := range pool.mined {
+		for i, sender := range senders[1:] {
+			if *tx.To() != sender.addr {
+				continue
+			}
+			if tx.Value().Cmp(funding) == 0 {
+				funded[i+1] = true
+			} else if !funded[i+1] {
+				t.Errorf("sender %d sent a transaction in block %d before it was funded", i+1, block+1)
+			}
+		}
+	}
+	if len(funded) != 2 {
+		t.Errorf("expected senders 1 and 2 funded, got %v", funded)
+	}
+
+	// The first sender also sent the transaction opening the scenario
+	for i, expected := range []uint64{2, 1 + concurrencyTxsPerSender + 1, concurrencyTxsPerSender} {
+		if nonce := pool.nonces[senders[i].addr]; nonce != expected {
+			t.Errorf("sender %d: expected nonce %d, got %d", i, expected, nonce)
+		}
+	}
+}
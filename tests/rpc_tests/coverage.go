@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// negativeTag marks the test cases checking how a method fails, e.g. on invalid params.
+const negativeTag = "negative"
+
+// coverageNamespaces are the namespaces reported on, in order.
+var coverageNamespaces = []string{"eth", "bor", "debug", "txpool", "net", "web3"}
+
+// knownMethods lists the methods Bor serves in each namespace of the report. rpc_modules
+// only returns the enabled namespaces, so their methods have to be known beforehand.
+var knownMethods = map[string][]string{
+	"eth": {
+		"accounts", "blobBaseFee", "blockNumber", "call", "chainId", "createAccessList", "estimateGas", "feeHistory",
+		"fillTransaction", "gasPrice", "getBalance", "getBlockByHash", "getBlockByNumber", "getBlockReceipts",
+		"getBlockTransactionCountByHash", "getBlockTransactionCountByNumber", "getBorBlockReceipt", "getCode",
+		"getFilterChanges", "getFilterLogs", "getHeaderByHash", "getHeaderByNumber", "getLogs", "getProof",
+		"getRawTransactionByBlockHashAndIndex", "getRawTransactionByBlockNumberAndIndex", "getRawTransactionByHash",
+		"getRootHash", "getStorageAt", "getTransactionByBlockHashAndIndex", "getTransactionByBlockNumberAndIndex",
+		"getTransactionByHash", "getTransactionCount", "getTransactionReceipt", "getTransactionReceiptsByBlock",
+		"getUncleByBlockHashAndIndex", "getUncleByBlockNumberAndIndex", "getUncleCountByBlockHash",
+		"getUncleCountByBlockNumber", "maxPriorityFeePerGas", "newBlockFilter", "newFilter",
+		"newPendingTransactionFilter", "pendingTransactions", "resend", "sendRawTransaction", "sendTransaction", "sign",
+		"signTransaction", "simulateV1", "subscribe", "syncing", "uninstallFilter", "unsubscribe",
+	},
+	"bor": {
+		"getAuthor", "getCurrentProposer", "getCurrentValidators", "getRootHash", "getSigners", "getSignersAtHash",
+		"getSnapshot", "getSnapshotAtHash", "getSnapshotProposer", "getSnapshotProposerSequence", "getVoteOnHash",
+	},
+	"debug": {
+		"accountRange", "blockProfile", "chaindbCompact", "chaindbProperty", "cpuProfile", "dumpBlock", "freeOSMemory",
+		"gcStats", "getAccessibleState", "getBadBlocks", "getModifiedAccountsByHash", "getModifiedAccountsByNumber",
+		"getRawBlock", "getRawHeader", "getRawReceipts", "getRawTransaction", "getTrieFlushInterval", "goTrace",
+		"intermediateRoots", "memStats", "mutexProfile", "preimage", "printBlock", "setBlockProfileRate",
+		"setGCPercent", "setHead", "setMemoryLimit", "setMutexProfileFraction", "setTrieFlushInterval", "stacks",
+		"standardTraceBadBlockToFile", "standardTraceBlockToFile", "startCPUProfile", "stopCPUProfile",
+		"storageRangeAt", "traceBadBlock", "traceBlock", "traceBlockByHash", "traceBlockByNumber",
+		"traceBlockFromFile", "traceCall", "traceChain", "traceTransaction", "verbosity", "vmodule",
+		"writeBlockProfile", "writeMemProfile", "writeMutexProfile",
+	},
+	"txpool": {"content", "contentFrom", "inspect", "status"},
+	"net":    {"listening", "peerCount", "version"},
+	"web3":   {"clientVersion", "sha3"},
+}
+
+// MethodCoverage lists the test cases calling a method.
+type MethodCoverage struct {
+	Method    string   `json:"method"`
+	TestCases []string `json:"testCases,omitempty"`
+}
+
+// NamespaceCoverage splits the methods of a namespace into tested, tested only on the
+// happy path and untested ones.
+type NamespaceCoverage struct {
+	Namespace     string           `json:"namespace"`
+	Enabled       bool             `json:"enabled"`
+	Tested        []MethodCoverage `json:"tested"`
+	HappyPathOnly []MethodCoverage `json:"happyPathOnly"`
+	Untested      []MethodCoverage `json:"untested"`
+}
+
+// testCaseMethod returns the method called by a test case, or "" if it is unknown.
+func testCaseMethod(testCase TestCase) string {
+	if testCase.Method != "" {
+		return testCase.Method
+	}
+	if match := methodInKeyRegex.FindStringSubmatch(testCase.Key); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func fetchModules(client RPCClient) (map[string]string, error) {
+	responses, err := client.Call([]Request{*NewRequest("rpc_modules", []interface{}{})})
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) != 1 {
+		return nil, fmt.Errorf("expected 1 response, got %d", len(responses))
+	}
+	if responses[0].Error != nil {
+		return nil, fmt.Errorf("request error; message: %s | code: %d", responses[0].Error.Message, responses[0].Error.Code)
+	}
+	modules, err := parseResponse[map[string]string](responses[0].Result)
+	if err != nil {
+		return nil, err
+	}
+	return *modules, nil
+}
+
+// computeCoverage maps the methods called by the test cases onto the known methods of
+// each namespace. A nil modules map means that every namespace is considered enabled.
+func computeCoverage(testCases []TestCase, modules map[string]string) []NamespaceCoverage {
+	testCasesByMethod := make(map[string][]TestCase)
+	for _, testCase := range testCases {
+		if method := testCaseMethod(testCase); method != "" {
+			testCasesByMethod[method] = append(testCasesByMethod[method], testCase)
+		}
+	}
+
+	coverage := make([]NamespaceCoverage, 0, len(coverageNamespaces))
+	for _, namespace := range coverageNamespaces {
+		_, enabled := modules[namespace]
+		namespaceCoverage := NamespaceCoverage{Namespace: namespace, Enabled: enabled || modules == nil}
+
+		// Methods called by a test case are reported even when they are not known
+		methods := make(map[string]bool)
+		for _, name := range knownMethods[namespace] {
+			methods[namespace+"_"+name] = true
+		}
+		for method := range testCasesByMethod {
+			if strings.HasPrefix(method, namespace+"_") {
+				methods[method] = true
+			}
+		}
+		sorted := make([]string, 0, len(methods))
+		for method := range methods {
+			sorted = append(sorted, method)
+		}
+		sort.Strings(sorted)
+
+		for _, method := range sorted {
+			methodCoverage := MethodCoverage{Method: method}
+			happyPathOnly := true
+			for _, testCase := range testCasesByMethod[method] {
+				methodCoverage.TestCases = append(methodCoverage.TestCases, testCase.Key)
+				for _, tag := range testCaseTags(testCase) {
+					if tag == negativeTag {
+						happyPathOnly = false
+					}
+				}
+			}
+			switch {
+			case len(methodCoverage.TestCases) == 0:
+				namespaceCoverage.Untested = append(namespaceCoverage.Untested, methodCoverage)
+			case happyPathOnly:
+				namespaceCoverage.HappyPathOnly = append(namespaceCoverage.HappyPathOnly, methodCoverage)
+			default:
+				namespaceCoverage.Tested = append(namespaceCoverage.Tested, methodCoverage)
+			}
+		}
+		coverage = append(coverage, namespaceCoverage)
+	}
+	return coverage
+}
+
+func printCoverage(coverage []NamespaceCoverage) {
+	fmt.Printf("\n📊  RPC Method Coverage:\n")
+	for _, namespace := range coverage {
+		if !namespace.Enabled {
+			fmt.Printf("\n  %s: not enabled on the node\n", namespace.Namespace)
+			continue
+		}
+		tested := len(namespace.Tested) + len(namespace.HappyPathOnly)
+		total := tested + len(namespace.Untested)
+		fmt.Printf("\n  %s: %d/%d methods tested, %d only on the happy path\n", namespace.Namespace, tested, total, len(namespace.HappyPathOnly))
+		for _, method := range namespace.Tested {
+			fmt.Printf("    ✅ %s (%d test cases)\n", method.Method, len(method.TestCases))
+		}
+		for _, method := range namespace.HappyPathOnly {
+			fmt.Printf("    ☑️  %s (%d test cases, happy path only)\n", method.Method, len(method.TestCases))
+		}
+		for _, method := range namespace.Untested {
+			fmt.Printf("    ❌ %s\n", method.Method)
+		}
+	}
+}
+
+// writeCoverageReport writes the coverage as JSON into dir.
+func writeCoverageReport(dir string, coverage []NamespaceCoverage) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating report directory: %w", err)
+	}
+	jsonBytes, err := json.MarshalIndent(coverage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling coverage report: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, coverageReportFileName), jsonBytes, 0644); err != nil {
+		return fmt.Errorf("error writing coverage report: %w", err)
+	}
+	return nil
+}
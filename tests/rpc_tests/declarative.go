@@ -0,0 +1,542 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"gopkg.in/yaml.v3"
+)
+
+// declarativeFile is the content of a YAML or JSON file holding declarative test cases:
+//
+//	testCases:
+//	  - key: "eth_getBlockByHash (latest)"
+//	    method: eth_getBlockByHash
+//	    tags: [blocks]
+//	    params: ["{{latestBlockHash}}", false]
+//	    assert:
+//	      - path: result.hash
+//	        equals: "{{latestBlockHash}}"
+//	      - path: result.transactions
+//	        exists: true
+//	    capture:
+//	      latestParentHash: result.parentHash
+//	  - key: "eth_getBlockByHash (invalid hash)"
+//	    method: eth_getBlockByHash
+//	    params: ["0x1234", false]
+//	    expectError:
+//	      code: -32602
+//	      message: "invalid argument 0"
+//
+// Params and expected values can reference the variables listed in builtinVariables and
+// the ones captured by other declarative test cases with {{name}}.
+type declarativeFile struct {
+	TestCases []declarativeTestCase `yaml:"testCases"`
+}
+
+type declarativeTestCase struct {
+	Key     string                 `yaml:"key"`
+	Method  string                 `yaml:"method"`
+	Tags    []string               `yaml:"tags"`
+	Params  []interface{}          `yaml:"params"`
+	Assert  []declarativeAssertion `yaml:"assert"`
+	Capture map[string]string      `yaml:"capture"`
+	// ExpectError makes the test case pass only on that error; assertions can then check
+	// error.code, error.message and error.data.
+	ExpectError *declarativeExpectedError `yaml:"expectError"`
+}
+
+type declarativeExpectedError struct {
+	Code    int    `yaml:"code"`
+	Message string `yaml:"message"`
+}
+
+// declarativeAssertion checks the value found at Path in the response. Every check that
+// is set must hold; numbers given to the comparisons can be hex quantities or decimals.
+type declarativeAssertion struct {
+	Path    string      `yaml:"path"`
+	Exists  *bool       `yaml:"exists"`
+	Equals  yaml.Node   `yaml:"equals"`
+	Matches string      `yaml:"matches"`
+	Length  *int        `yaml:"length"`
+	Gt      interface{} `yaml:"gt"`
+	Gte     interface{} `yaml:"gte"`
+	Lt      interface{} `yaml:"lt"`
+	Lte     interface{} `yaml:"lte"`
+}
+
+// builtinVariable exposes a ResponseMap field to declarative test cases. Field names the
+// ResponseMap field the value comes from so the test case is scheduled after its producer;
+// it is empty for values set before the run starts.
+type builtinVariable struct {
+	field string
+	value func(rm *ResponseMap) interface{}
+}
+
+var builtinVariables = map[string]builtinVariable{
+	"accountAddress":                  {value: func(rm *ResponseMap) interface{} { return rm.account.addr }},
+	"chainId":                         {field: "chainId", value: func(rm *ResponseMap) interface{} { return (*hexutil.Big)(rm.chainId) }},
+	"gasPrice":                        {field: "gasPrice", value: func(rm *ResponseMap) interface{} { return (*hexutil.Big)(rm.gasPrice) }},
+	"mostRecentBlockNumber":           {field: "mostRecentBlockNumber", value: func(rm *ResponseMap) interface{} { return (*hexutil.Big)(rm.mostRecentBlockNumber) }},
+	"mostRecentBlockHash":             {field: "mostRecentBlockHash", value: func(rm *ResponseMap) interface{} { return rm.mostRecentBlockHash }},
+	"mostRecentBlockParentHash":       {field: "mostRecentBlockParentHash", value: func(rm *ResponseMap) interface{} { return rm.mostRecentBlockParentHash }},
+	"currentProposerAddress":          {field: "currentProposerAddress", value: func(rm *ResponseMap) interface{} { return rm.currentProposerAddress }},
+	"stateSyncTxHash":                 {field: "stateSyncTxHash", value: func(rm *ResponseMap) interface{} { return rm.stateSyncTxHash }},
+	"stateSyncBlockNumber":            {field: "stateSyncBlockNumber", value: func(rm *ResponseMap) interface{} { return (*hexutil.Big)(rm.stateSyncBlockNumber) }},
+	"stateSyncBlockHash":              {field: "stateSyncBlockHash", value: func(rm *ResponseMap) interface{} { return rm.stateSyncBlockHash }},
+	"pushedTxHash":                    {field: "pushedTxHash", value: func(rm *ResponseMap) interface{} { return rm.pushedTxHash }},
+	"pushedTxBlockNumber":             {field: "pushedTxBlockNumber", value: func(rm *ResponseMap) interface{} { return (*hexutil.Big)(rm.pushedTxBlockNumber) }},
+	"pushedTxBlockHash":               {field: "pushedTxBlockHash", value: func(rm *ResponseMap) interface{} { return rm.pushedTxBlockHash }},
+	"pushedTxDeployedContractAddress": {field: "pushedTxDeployedContractAddress", value: func(rm *ResponseMap) interface{} { return rm.pushedTxDeployedContractAddress }},
+}
+
+var (
+	templateVariableRegex = regexp.MustCompile(`\{\{\s*([A-Za-z][A-Za-z0-9_]*)\s*\}\}`)
+	variableNameRegex     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+)
+
+// loadDeclarativeTestCases reads the declarative test cases of a comma separated list of
+// files and directories. Directories are read non recursively, keeping only .yaml, .yml
+// and .json files, in lexical order.
+func loadDeclarativeTestCases(paths string) ([]TestCase, error) {
+	var files []string
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+
+	var testCases []TestCase
+	captured := make(map[string]string)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		// JSON is valid YAML, so a single decoder handles both formats
+		var parsed declarativeFile
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&parsed); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+
+		for _, definition := range parsed.TestCases {
+			for name := range definition.Capture {
+				if other, ok := captured[name]; ok {
+					return nil, fmt.Errorf("%s: variable %q captured by both %q and %q", file, name, other, definition.Key)
+				}
+				captured[name] = definition.Key
+			}
+			testCase, err := newDeclarativeTestCase(definition)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			testCases = append(testCases, testCase)
+		}
+	}
+	return testCases, nil
+}
+
+// newDeclarativeTestCase validates a definition and turns it into a TestCase.
+func newDeclarativeTestCase(definition declarativeTestCase) (TestCase, error) {
+	if definition.Key == "" {
+		return TestCase{}, fmt.Errorf("test case without key")
+	}
+	if definition.Method == "" {
+		return TestCase{}, fmt.Errorf("test case %q has no method", definition.Key)
+	}
+	for i, assertion := range definition.Assert {
+		if assertion.Path == "" {
+			return TestCase{}, fmt.Errorf("test case %q: assertion %d has no path", definition.Key, i)
+		}
+		if assertion.Matches != "" {
+			if _, err := regexp.Compile(assertion.Matches); err != nil {
+				return TestCase{}, fmt.Errorf("test case %q: assertion %d: %w", definition.Key, i, err)
+			}
+		}
+	}
+
+	var expectError *ExpectedError
+	if definition.ExpectError != nil {
+		if _, err := regexp.Compile(definition.ExpectError.Message); err != nil {
+			return TestCase{}, fmt.Errorf("test case %q: invalid expected error message: %w", definition.Key, err)
+		}
+		expectError = &ExpectedError{Code: definition.ExpectError.Code, Message: definition.ExpectError.Message}
+	}
+
+	var produces []string
+	for name := range definition.Capture {
+		if !variableNameRegex.MatchString(name) {
+			return TestCase{}, fmt.Errorf("test case %q: invalid variable name %q", definition.Key, name)
+		}
+		if _, ok := builtinVariables[name]; ok {
+			return TestCase{}, fmt.Errorf("test case %q: variable %q is built in and cannot be captured", definition.Key, name)
+		}
+		produces = append(produces, name)
+	}
+	sort.Strings(produces)
+
+	// Every variable used in params or expected values is a dependency
+	referenced := make(map[string]bool)
+	collectTemplateVariables(definition.Params, referenced)
+	for _, assertion := range definition.Assert {
+		var expected interface{}
+		if assertion.Equals.Kind != 0 {
+			if err := assertion.Equals.Decode(&expected); err != nil {
+				return TestCase{}, fmt.Errorf("test case %q: invalid expected value: %w", definition.Key, err)
+			}
+		}
+		collectTemplateVariables([]interface{}{expected, assertion.Gt, assertion.Gte, assertion.Lt, assertion.Lte}, referenced)
+	}
+	var requires []string
+	for name := range referenced {
+		if builtin, ok := builtinVariables[name]; ok {
+			if builtin.field != "" {
+				requires = append(requires, builtin.field)
+			}
+			continue
+		}
+		requires = append(requires, name)
+	}
+	sort.Strings(requires)
+
+	return TestCase{
+		Key:         definition.Key,
+		Method:      definition.Method,
+		Requires:    requires,
+		Produces:    produces,
+		Tags:        declarativeTags(definition),
+		ExpectError: expectError,
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			params, err := renderTemplate(definition.Params, rm)
+			if err != nil {
+				return nil, err
+			}
+			if params == nil {
+				params = []interface{}{}
+			}
+			return NewRequest(definition.Method, params), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			value, err := responseValue(resp)
+			if err != nil {
+				return err
+			}
+			for _, assertion := range definition.Assert {
+				if err := assertion.check(value, rm); err != nil {
+					return err
+				}
+			}
+			for name, path := range definition.Capture {
+				captured, ok := lookupPath(value, path)
+				if !ok {
+					return fmt.Errorf("cannot capture %q: nothing at %s", name, path)
+				}
+				if rm.variables == nil {
+					rm.variables = make(map[string]interface{})
+				}
+				rm.variables[name] = captured
+			}
+			return nil
+		},
+	}, nil
+}
+
+// declarativeTags adds the "declarative" tag to the ones listed in the definition, since
+// the key of a declarative test case is free form. The tags derived from the method come
+// from the Method of the test case.
+func declarativeTags(definition declarativeTestCase) []string {
+	return append([]string{"declarative"}, definition.Tags...)
+}
+
+func collectTemplateVariables(value interface{}, names map[string]bool) {
+	switch v := value.(type) {
+	case string:
+		for _, match := range templateVariableRegex.FindAllStringSubmatch(v, -1) {
+			names[match[1]] = true
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectTemplateVariables(item, names)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			collectTemplateVariables(item, names)
+		}
+	}
+}
+
+// lookupVariable returns the value of a built in or captured variable as it would be
+// decoded from a JSON-RPC response.
+func lookupVariable(name string, rm *ResponseMap) (interface{}, error) {
+	if value, ok := rm.variables[name]; ok {
+		return value, nil
+	}
+	builtin, ok := builtinVariables[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %q", name)
+	}
+	raw, err := json.Marshal(builtin.value(rm))
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling variable %q: %w", name, err)
+	}
+	return decodeJSON(raw)
+}
+
+// renderTemplate returns a copy of value where {{name}} references are replaced. A string
+// made of a single reference takes the variable value as is, so it can be a number, a
+// bool or an object; references inside a longer string are formatted into it.
+func renderTemplate(value interface{}, rm *ResponseMap) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if match := templateVariableRegex.FindStringSubmatchIndex(v); match != nil && match[0] == 0 && match[1] == len(v) {
+			return lookupVariable(v[match[2]:match[3]], rm)
+		}
+		var renderErr error
+		rendered := templateVariableRegex.ReplaceAllStringFunc(v, func(reference string) string {
+			name := templateVariableRegex.FindStringSubmatch(reference)[1]
+			variable, err := lookupVariable(name, rm)
+			if err != nil {
+				renderErr = err
+				return reference
+			}
+			if s, ok := variable.(string); ok {
+				return s
+			}
+			raw, _ := json.Marshal(variable)
+			return string(raw)
+		})
+		return rendered, renderErr
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if rendered[i], err = renderTemplate(item, rm); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			var err error
+			if rendered[key], err = renderTemplate(item, rm); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	}
+	return value, nil
+}
+
+// lookupPath returns the value at a path such as "result.transactions[0].hash",
+// "$.result.transactions.0.hash" being equivalent.
+func lookupPath(value interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	current := value
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			continue
+		}
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			current = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func (a declarativeAssertion) check(response interface{}, rm *ResponseMap) error {
+	actual, found := lookupPath(response, a.Path)
+	if a.Exists != nil && *a.Exists != found {
+		if found {
+			return fmt.Errorf("%s: expected no value, got %s", a.Path, formatValue(actual))
+		}
+		return fmt.Errorf("%s: expected a value, got none", a.Path)
+	}
+	if !found {
+		if a.Exists != nil {
+			return nil
+		}
+		return fmt.Errorf("%s: no value found", a.Path)
+	}
+
+	if a.Equals.Kind != 0 {
+		var expected interface{}
+		if err := a.Equals.Decode(&expected); err != nil {
+			return err
+		}
+		expected, err := renderTemplate(expected, rm)
+		if err != nil {
+			return err
+		}
+		if formatValue(expected) != formatValue(actual) {
+			return fmt.Errorf("%s: expected %s, got %s", a.Path, formatValue(expected), formatValue(actual))
+		}
+	}
+
+	if a.Matches != "" {
+		s, ok := actual.(string)
+		if !ok {
+			s = formatValue(actual)
+		}
+		if !regexp.MustCompile(a.Matches).MatchString(s) {
+			return fmt.Errorf("%s: %s does not match %q", a.Path, formatValue(actual), a.Matches)
+		}
+	}
+
+	if a.Length != nil {
+		var length int
+		switch v := actual.(type) {
+		case []interface{}:
+			length = len(v)
+		case map[string]interface{}:
+			length = len(v)
+		case string:
+			length = len(v)
+		default:
+			return fmt.Errorf("%s: %s has no length", a.Path, formatValue(actual))
+		}
+		if length != *a.Length {
+			return fmt.Errorf("%s: expected length %d, got %d", a.Path, *a.Length, length)
+		}
+	}
+
+	comparisons := []struct {
+		bound interface{}
+		name  string
+		holds func(cmp int) bool
+	}{
+		{a.Gt, "greater than", func(cmp int) bool { return cmp > 0 }},
+		{a.Gte, "greater than or equal to", func(cmp int) bool { return cmp >= 0 }},
+		{a.Lt, "less than", func(cmp int) bool { return cmp < 0 }},
+		{a.Lte, "less than or equal to", func(cmp int) bool { return cmp <= 0 }},
+	}
+	for _, comparison := range comparisons {
+		if comparison.bound == nil {
+			continue
+		}
+		bound, err := renderTemplate(comparison.bound, rm)
+		if err != nil {
+			return err
+		}
+		boundInt, err := toBigInt(bound)
+		if err != nil {
+			return fmt.Errorf("%s: invalid bound: %w", a.Path, err)
+		}
+		actualInt, err := toBigInt(actual)
+		if err != nil {
+			return fmt.Errorf("%s: %w", a.Path, err)
+		}
+		if !comparison.holds(actualInt.Cmp(boundInt)) {
+			return fmt.Errorf("%s: expected a value %s %s, got %s", a.Path, comparison.name, boundInt, actualInt)
+		}
+	}
+	return nil
+}
+
+// toBigInt converts a hex quantity, a decimal string or a number to a big.Int.
+func toBigInt(value interface{}) (*big.Int, error) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	case int:
+		return big.NewInt(int64(v)), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	default:
+		return nil, fmt.Errorf("%s is not a number", formatValue(value))
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return hexStringToBigInt(s)
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a number", s)
+	}
+	return n, nil
+}
+
+// formatValue returns the canonical JSON encoding of a value, used to compare values
+// decoded from YAML with values decoded from a response.
+func formatValue(value interface{}) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(raw)
+}
+
+// checkDeclarativeTestCases rejects declarative test cases whose key or captured
+// variables clash with the ones of the built-in test cases.
+func checkDeclarativeTestCases(builtin, declared []TestCase) error {
+	keys := make(map[string]bool)
+	fields := make(map[string]bool)
+	for _, testCase := range builtin {
+		keys[testCase.Key] = true
+		for _, field := range testCase.Produces {
+			fields[field] = true
+		}
+	}
+	for _, testCase := range declared {
+		if keys[testCase.Key] {
+			return fmt.Errorf("duplicate test case key %q", testCase.Key)
+		}
+		keys[testCase.Key] = true
+		for _, name := range testCase.Produces {
+			if fields[name] {
+				return fmt.Errorf("test case %q: variable %q is already produced by a built-in test case", testCase.Key, name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	rm := &ResponseMap{
+		mostRecentBlockNumber: big.NewInt(255),
+		mostRecentBlockHash:   common.HexToHash("0x01"),
+		variables: map[string]interface{}{
+			"count":  json.Number("3"),
+			"object": map[string]interface{}{"a": true},
+		},
+	}
+	for _, tt := range []struct {
+		template interface{}
+		expected interface{}
+		err      string
+	}{
+		{"{{mostRecentBlockNumber}}", "0xff", ""},
+		{"{{ count }}", json.Number("3"), ""},
+		{"{{object}}", map[string]interface{}{"a": true}, ""},
+		{"block {{mostRecentBlockNumber}} has {{count}} {{object}}", `block 0xff has 3 {"a":true}`, ""},
+		{[]interface{}{"{{mostRecentBlockHash}}", false}, []interface{}{common.HexToHash("0x01").Hex(), false}, ""},
+		{map[string]interface{}{"toBlock": "{{mostRecentBlockNumber}}"}, map[string]interface{}{"toBlock": "0xff"}, ""},
+		{"no {{ reference", "no {{ reference", ""},
+		{"{{unknown}}", nil, `unknown variable "unknown"`},
+		{[]interface{}{"prefix {{unknown}}"}, nil, `unknown variable "unknown"`},
+	} {
+		rendered, err := renderTemplate(tt.template, rm)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%v: expected error %q, got %v", tt.template, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.template, err)
+			continue
+		}
+		if !reflect.DeepEqual(rendered, tt.expected) {
+			t.Errorf("%v: expected %#v, got %#v", tt.template, tt.expected, rendered)
+		}
+	}
+}
+
+func TestDeclarativeAssertions(t *testing.T) {
+	response, err := decodeJSON([]byte(`{"result": {"number": "0x10", "miner": "0xabc", "transactions": [{"hash": "0x01"}], "uncles": []}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm := &ResponseMap{mostRecentBlockNumber: big.NewInt(16)}
+	for _, tt := range []struct {
+		assertion string
+		err       string
+	}{
+		{`{path: result.number, equals: "{{mostRecentBlockNumber}}"}`, ""},
+		{`{path: "$.result.transactions[0].hash", equals: "0x01"}`, ""},
+		{`{path: result.transactions.0.hash, equals: "0x02"}`, `result.transactions.0.hash: expected "0x02", got "0x01"`},
+		{`{path: result.miner, matches: "^0x[0-9a-f]+$"}`, ""},
+		{`{path: result.miner, matches: "^0xdef"}`, `result.miner: "0xabc" does not match "^0xdef"`},
+		{`{path: result.uncles, exists: true, length: 0}`, ""},
+		{`{path: result.transactions, length: 2}`, "result.transactions: expected length 2, got 1"},
+		{`{path: result.baseFeePerGas, exists: false}`, ""},
+		{`{path: result.baseFeePerGas}`, "result.baseFeePerGas: no value found"},
+		{`{path: result.number, exists: false}`, `result.number: expected no value, got "0x10"`},
+		{`{path: result.number, gt: 15, lte: "0x10"}`, ""},
+		{`{path: result.number, gte: "{{mostRecentBlockNumber}}", lt: "17"}`, ""},
+		{`{path: result.number, gt: 16}`, "result.number: expected a value greater than 16, got 16"},
+		{`{path: result.number, lt: sixteen}`, `result.number: invalid bound: "sixteen" is not a number`},
+		{`{path: result.uncles, gt: 0}`, "result.uncles: [] is not a number"},
+	} {
+		var assertion declarativeAssertion
+		if err := yaml.Unmarshal([]byte(tt.assertion), &assertion); err != nil {
+			t.Fatalf("%s: %v", tt.assertion, err)
+		}
+		err := assertion.check(response, rm)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.assertion, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: expected error %q, got %v", tt.assertion, tt.err, err)
+		}
+	}
+}
+
+func TestLoadDeclarativeTestCases(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	writeFile("a.yaml", `
+testCases:
+  - key: "capture the block"
+    method: eth_getBlockByNumber
+    params: ["{{mostRecentBlockNumber}}", false]
+    capture:
+      blockHash: result.hash
+`)
+	writeFile("b.json", `{"testCases": [{
+		"key": "use the block",
+		"method": "eth_getBlockByHash",
+		"params": ["{{blockHash}}", false],
+		"assert": [{"path": "result.number", "equals": "{{mostRecentBlockNumber}}"}]
+	}]}`)
+	writeFile("ignored.txt", "not a test case")
+
+	testCases, err := loadDeclarativeTestCases(dir)
+	if err != nil {
+		t.Fatalf("loadDeclarativeTestCases: %v", err)
+	}
+	if keys := testCaseKeys(testCases); !reflect.DeepEqual(keys, []string{"capture the block", "use the block"}) {
+		t.Fatalf("expected the test cases of a.yaml then b.json, got %v", keys)
+	}
+	capture, use := testCases[0], testCases[1]
+	if !reflect.DeepEqual(capture.Requires, []string{"mostRecentBlockNumber"}) || !reflect.DeepEqual(capture.Produces, []string{"blockHash"}) {
+		t.Errorf("capture the block: expected to require mostRecentBlockNumber and produce blockHash, got %v and %v", capture.Requires, capture.Produces)
+	}
+	if !reflect.DeepEqual(use.Requires, []string{"blockHash", "mostRecentBlockNumber"}) {
+		t.Errorf("use the block: expected to require blockHash and mostRecentBlockNumber, got %v", use.Requires)
+	}
+
+	rm := &ResponseMap{mostRecentBlockNumber: big.NewInt(16)}
+	req, err := capture.PrepareRequest(rm)
+	if err != nil {
+		t.Fatalf("capture the block: PrepareRequest: %v", err)
+	}
+	if params, _ := json.Marshal(req.Params); req.Method != "eth_getBlockByNumber" || string(params) != `["0x10",false]` {
+		t.Errorf("capture the block: expected eth_getBlockByNumber with [\"0x10\",false], got %s with %s", req.Method, params)
+	}
+	if err := capture.HandleResponse(rm, Response{Result: json.RawMessage(`{"hash": "0xbeef", "number": "0x10"}`)}); err != nil {
+		t.Fatalf("capture the block: HandleResponse: %v", err)
+	}
+	if rm.variables["blockHash"] != "0xbeef" {
+		t.Errorf("expected blockHash captured, got %v", rm.variables)
+	}
+	req, err = use.PrepareRequest(rm)
+	if err != nil {
+		t.Fatalf("use the block: PrepareRequest: %v", err)
+	}
+	if params, _ := json.Marshal(req.Params); string(params) != `["0xbeef",false]` {
+		t.Errorf("use the block: expected the captured hash in the params, got %s", params)
+	}
+	if err := use.HandleResponse(rm, Response{Result: json.RawMessage(`{"number": "0x11"}`)}); err == nil {
+		t.Error("use the block: expected the number assertion to fail")
+	}
+	if err := capture.HandleResponse(rm, Response{Result: json.RawMessage(`{"number": "0x10"}`)}); err == nil {
+		t.Error("capture the block: expected an error capturing a missing field")
+	}
+}
+
+func TestLoadDeclarativeTestCasesErrors(t *testing.T) {
+	for _, tt := range []struct {
+		content string
+		err     string
+	}{
+		{`testCases: [{method: eth_chainId}]`, "test case without key"},
+		{`testCases: [{key: k}]`, `test case "k" has no method`},
+		{`testCases: [{key: k, method: eth_chainId, unknown: 1}]`, "field unknown not found"},
+		{`testCases: [{key: k, method: eth_chainId, assert: [{equals: 1}]}]`, `test case "k": assertion 0 has no path`},
+		{`testCases: [{key: k, method: eth_chainId, assert: [{path: result, matches: "("}]}]`, "missing closing )"},
+		{`testCases: [{key: k, method: eth_chainId, capture: {chainId: result}}]`, `variable "chainId" is built in and cannot be captured`},
+		{`testCases: [{key: k, method: eth_chainId, capture: {"1x": result}}]`, `invalid variable name "1x"`},
+		{`testCases: [{key: a, method: eth_chainId, capture: {x: result}}, {key: b, method: eth_chainId, capture: {x: result}}]`, `variable "x" captured by both "a" and "b"`},
+	} {
+		path := filepath.Join(t.TempDir(), "testcases.yaml")
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadDeclarativeTestCases(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: expected error %q, got %v", tt.content, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// defaultDiffIgnoreRules skips methods whose result depends on the node's head or
+// txpool rather than on a block, so they cannot be pinned and legitimately differ.
+var defaultDiffIgnoreRules = []string{
+	"eth_blockNumber",
+	"eth_gasPrice",
+	"eth_maxPriorityFeePerGas",
+	"eth_syncing",
+	"eth_fillTransaction",
+	"web3_clientVersion",
+	"bor_getCurrentProposer",
+	"bor_getCurrentValidators",
+	"bor_getSnapshotProposer",
+	"bor_getSnapshotProposerSequence",
+}
+
+// optionalBlockParamIndex holds, for methods whose block parameter is optional and
+// defaults to the head, the position at which a pinned block must be appended.
+var optionalBlockParamIndex = map[string]int{
+	"bor_getAuthor":        0,
+	"eth_estimateGas":      1,
+	"eth_createAccessList": 1,
+}
+
+// blockTags are the block parameters that resolve to a different block on each node.
+var blockTags = map[string]bool{
+	"latest":    true,
+	"pending":   true,
+	"safe":      true,
+	"finalized": true,
+}
+
+// FieldDiff is a single difference between the primary and the baseline response.
+type FieldDiff struct {
+	Path     string      `json:"path"`
+	Primary  interface{} `json:"primary"`
+	Baseline interface{} `json:"baseline"`
+}
+
+// diffIgnoreRule ignores a whole method when path is empty, or the fields matching
+// path otherwise. Method and path segments can be "*" to match anything.
+type diffIgnoreRule struct {
+	method string
+	path   []string
+}
+
+// parseDiffIgnoreRules parses a comma separated list of "method" or "method:path"
+// entries, where path is a dot separated field path such as "result.transactions.*.gasPrice".
+func parseDiffIgnoreRules(spec string) []diffIgnoreRule {
+	var rules []diffIgnoreRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		method, path, _ := strings.Cut(entry, ":")
+		rule := diffIgnoreRule{method: method}
+		if path != "" {
+			rule.path = strings.Split(path, ".")
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (r diffIgnoreRule) matchesMethod(method string) bool {
+	return r.method == "*" || r.method == method
+}
+
+// matchesPath reports whether path is the ignored field or one of its children.
+func (r diffIgnoreRule) matchesPath(path []string) bool {
+	if len(path) < len(r.path) {
+		return false
+	}
+	for i, segment := range r.path {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isMethodIgnored(method string, rules []diffIgnoreRule) bool {
+	for _, rule := range rules {
+		if len(rule.path) == 0 && rule.matchesMethod(method) {
+			return true
+		}
+	}
+	return false
+}
+
+func isFieldIgnored(method string, path []string, rules []diffIgnoreRule) bool {
+	for _, rule := range rules {
+		if len(rule.path) > 0 && rule.matchesMethod(method) && rule.matchesPath(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareWithBaseline re-sends the request of every passed, read-only test case to both
+// the primary and the baseline endpoint, pinned to the same block, and marks the test
+// cases whose responses differ outside of the ignored fields as failed.
+func compareWithBaseline(client, baselineClient RPCClient, results []TestResult, rules []diffIgnoreRule) error {
+	pinnedBlock, err := fetchBlockNumber(client)
+	if err != nil {
+		return fmt.Errorf("error getting primary block number: %w", err)
+	}
+	if err := waitForBlockNumber(baselineClient, pinnedBlock, 30*time.Second, time.Second); err != nil {
+		return fmt.Errorf("baseline endpoint did not reach block %s: %w", pinnedBlock, err)
+	}
+	pinnedBlockHex := hexutil.EncodeBig(pinnedBlock)
+
+	var requests []Request
+	mapRequestIdToIndex := make(map[int]int)
+	for i, result := range results {
+		if result.Status != TestStatusPassed || result.Req == nil || result.Req.Raw != "" {
+			continue
+		}
+		if !isReadOnlyMethod(result.Req.Method) || isMethodIgnored(result.Req.Method, rules) {
+			continue
+		}
+		req, err := pinRequest(*result.Req, pinnedBlockHex)
+		if err != nil {
+			return fmt.Errorf("error pinning request of %q: %w", result.Key, err)
+		}
+		mapRequestIdToIndex[req.ID] = i
+		requests = append(requests, req)
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+
+	primaryResponses, err := client.Call(requests)
+	if err != nil {
+		return fmt.Errorf("error calling primary endpoint: %w", err)
+	}
+	baselineResponses, err := baselineClient.Call(requests)
+	if err != nil {
+		return fmt.Errorf("error calling baseline endpoint: %w", err)
+	}
+
+	baselineByID := make(map[int]Response, len(baselineResponses))
+	for _, response := range baselineResponses {
+		baselineByID[response.ID] = response
+	}
+
+	for _, primary := range primaryResponses {
+		i, ok := mapRequestIdToIndex[primary.ID]
+		if !ok {
+			continue
+		}
+		result := &results[i]
+		baseline, ok := baselineByID[primary.ID]
+		if !ok {
+			result.Status = TestStatusFailed
+			result.Err = fmt.Errorf("baseline endpoint returned no response for pinned block %s", pinnedBlockHex)
+			continue
+		}
+
+		diffs, err := diffResponses(result.Req.Method, primary, baseline, rules)
+		if err != nil {
+			result.Status = TestStatusFailed
+			result.Err = fmt.Errorf("error comparing with baseline: %w", err)
+			continue
+		}
+		if len(diffs) > 0 {
+			result.Diffs = diffs
+			result.Status = TestStatusFailed
+			result.Err = fmt.Errorf("response at pinned block %s differs from baseline in %d field(s)", pinnedBlockHex, len(diffs))
+		}
+	}
+
+	return nil
+}
+
+// pinRequest returns a copy of req whose block tags are replaced by blockHex.
+func pinRequest(req Request, blockHex string) (Request, error) {
+	raw, err := json.Marshal(req.Params)
+	if err != nil {
+		return Request{}, err
+	}
+	params, err := decodeJSON(raw)
+	if err != nil {
+		return Request{}, err
+	}
+
+	if list, ok := params.([]interface{}); ok {
+		if index, ok := optionalBlockParamIndex[req.Method]; ok && len(list) == index {
+			list = append(list, "latest")
+		}
+		params = list
+	}
+
+	pinned := req
+	pinned.Params = replaceBlockTags(params, blockHex)
+	return pinned, nil
+}
+
+func replaceBlockTags(value interface{}, blockHex string) interface{} {
+	switch v := value.(type) {
+	case string:
+		if blockTags[v] {
+			return blockHex
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = replaceBlockTags(v[i], blockHex)
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = replaceBlockTags(v[key], blockHex)
+		}
+	}
+	return value
+}
+
+// diffResponses compares the result, or the error, of two responses field by field.
+func diffResponses(method string, primary, baseline Response, rules []diffIgnoreRule) ([]FieldDiff, error) {
+	primaryValue, err := responseValue(primary)
+	if err != nil {
+		return nil, fmt.Errorf("invalid primary response: %w", err)
+	}
+	baselineValue, err := responseValue(baseline)
+	if err != nil {
+		return nil, fmt.Errorf("invalid baseline response: %w", err)
+	}
+
+	var diffs []FieldDiff
+	diffValues(method, nil, primaryValue, baselineValue, rules, &diffs)
+	return diffs, nil
+}
+
+func responseValue(response Response) (map[string]interface{}, error) {
+	if response.Error != nil {
+		rpcError := map[string]interface{}{
+			"code":    json.Number(strconv.Itoa(response.Error.Code)),
+			"message": response.Error.Message,
+		}
+		if len(response.Error.Data) > 0 {
+			data, err := decodeJSON(response.Error.Data)
+			if err != nil {
+				return nil, err
+			}
+			rpcError["data"] = data
+		}
+		return map[string]interface{}{"error": rpcError}, nil
+	}
+	result, err := decodeJSON(response.Result)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"result": result}, nil
+}
+
+func diffValues(method string, path []string, primary, baseline interface{}, rules []diffIgnoreRule, diffs *[]FieldDiff) {
+	if isFieldIgnored(method, path, rules) {
+		return
+	}
+
+	switch p := primary.(type) {
+	case map[string]interface{}:
+		b, ok := baseline.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for key := range p {
+			keys[key] = true
+		}
+		for key := range b {
+			keys[key] = true
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+		for _, key := range sortedKeys {
+			diffValues(method, appendPath(path, key), p[key], b[key], rules, diffs)
+		}
+		return
+	case []interface{}:
+		b, ok := baseline.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(p) || i < len(b); i++ {
+			var primaryItem, baselineItem interface{}
+			if i < len(p) {
+				primaryItem = p[i]
+			}
+			if i < len(b) {
+				baselineItem = b[i]
+			}
+			diffValues(method, appendPath(path, strconv.Itoa(i)), primaryItem, baselineItem, rules, diffs)
+		}
+		return
+	}
+
+	primaryJSON, _ := json.Marshal(primary)
+	baselineJSON, _ := json.Marshal(baseline)
+	if !bytes.Equal(primaryJSON, baselineJSON) {
+		*diffs = append(*diffs, FieldDiff{Path: strings.Join(path, "."), Primary: primary, Baseline: baseline})
+	}
+}
+
+func appendPath(path []string, segment string) []string {
+	newPath := make([]string, len(path), len(path)+1)
+	copy(newPath, path)
+	return append(newPath, segment)
+}
+
+// decodeJSON decodes raw keeping numbers as json.Number so no precision is lost.
+func decodeJSON(raw []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
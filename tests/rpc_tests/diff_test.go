@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPinRequest(t *testing.T) {
+	for _, tt := range []struct {
+		method   string
+		params   interface{}
+		expected string
+	}{
+		{"eth_getBalance", []interface{}{"0x01", "latest"}, `["0x01","0x10"]`},
+		{"eth_getBlockByNumber", []interface{}{"finalized", false}, `["0x10",false]`},
+		{"eth_getBlockByNumber", []interface{}{"0x05", true}, `["0x05",true]`},
+		{"eth_getLogs", []interface{}{map[string]interface{}{"fromBlock": "safe", "toBlock": "pending"}}, `[{"fromBlock":"0x10","toBlock":"0x10"}]`},
+		// The block parameter defaults to the head when left out, so it is appended
+		{"eth_estimateGas", []interface{}{map[string]interface{}{"to": "0x02"}}, `[{"to":"0x02"},"0x10"]`},
+		{"bor_getAuthor", []interface{}{}, `["0x10"]`},
+		{"bor_getAuthor", []interface{}{"0x05"}, `["0x05"]`},
+		// Large numbers keep their precision
+		{"eth_call", []interface{}{map[string]interface{}{"value": json.Number("123456789012345678901234567890")}, "latest"}, `[{"value":123456789012345678901234567890},"0x10"]`},
+	} {
+		req := Request{JsonRPC: "2.0", Method: tt.method, Params: tt.params, ID: 7}
+		pinned, err := pinRequest(req, "0x10")
+		if err != nil {
+			t.Fatalf("%s: pinRequest: %v", tt.method, err)
+		}
+		params, err := json.Marshal(pinned.Params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(params) != tt.expected {
+			t.Errorf("%s %v: expected params %s, got %s", tt.method, tt.params, tt.expected, params)
+		}
+		if pinned.Method != req.Method || pinned.ID != req.ID {
+			t.Errorf("%s: expected method and id kept, got %s and %d", tt.method, pinned.Method, pinned.ID)
+		}
+	}
+}
+
+func TestDiffResponses(t *testing.T) {
+	block := func(hash, gasPrice, miner string) Response {
+		return Response{Result: json.RawMessage(`{"hash":"` + hash + `","miner":"` + miner + `","transactions":[{"gasPrice":"` + gasPrice + `"}]}`)}
+	}
+	for _, tt := range []struct {
+		name     string
+		primary  Response
+		baseline Response
+		rules    string
+		expected []string
+	}{
+		{
+			name:     "identical",
+			primary:  block("0x01", "0x02", "0x03"),
+			baseline: block("0x01", "0x02", "0x03"),
+		},
+		{
+			name:     "nested differences are reported by path",
+			primary:  block("0x01", "0x02", "0x03"),
+			baseline: block("0x0a", "0x0b", "0x03"),
+			expected: []string{"result.hash", "result.transactions.0.gasPrice"},
+		},
+		{
+			name:     "ignored field and its wildcard siblings",
+			primary:  block("0x01", "0x02", "0x03"),
+			baseline: block("0x0a", "0x0b", "0x0c"),
+			rules:    "eth_getBlockByNumber:result.transactions.*.gasPrice,*:result.miner",
+			expected: []string{"result.hash"},
+		},
+		{
+			name:     "rule of another method",
+			primary:  block("0x01", "0x02", "0x03"),
+			baseline: block("0x01", "0x02", "0x0c"),
+			rules:    "eth_getBlockByHash:result.miner",
+			expected: []string{"result.miner"},
+		},
+		{
+			name:     "missing array items",
+			primary:  Response{Result: json.RawMessage(`[1,2]`)},
+			baseline: Response{Result: json.RawMessage(`[1]`)},
+			expected: []string{"result.1"},
+		},
+		{
+			name:     "error against result",
+			primary:  Response{Error: &RPCError{Code: -32000, Message: "header not found"}},
+			baseline: block("0x01", "0x02", "0x03"),
+			expected: []string{"error", "result"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := diffResponses("eth_getBlockByNumber", tt.primary, tt.baseline, parseDiffIgnoreRules(tt.rules))
+			if err != nil {
+				t.Fatalf("diffResponses: %v", err)
+			}
+			var paths []string
+			for _, diff := range diffs {
+				paths = append(paths, diff.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("expected differences at %v, got %v", tt.expected, paths)
+			}
+		})
+	}
+}
+
+func TestIsMethodIgnored(t *testing.T) {
+	rules := parseDiffIgnoreRules(" eth_gasPrice, eth_getBlockByNumber:result.hash ,")
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	if !isMethodIgnored("eth_gasPrice", rules) {
+		t.Error("expected eth_gasPrice ignored")
+	}
+	// A field rule does not ignore the whole method
+	if isMethodIgnored("eth_getBlockByNumber", rules) {
+		t.Error("expected eth_getBlockByNumber compared")
+	}
+	if !isMethodIgnored("eth_chainId", parseDiffIgnoreRules("*")) {
+		t.Error("expected * to ignore every method")
+	}
+}
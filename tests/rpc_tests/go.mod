@@ -3,8 +3,11 @@ module rpc-tests
 go 1.24.6
 
 require (
+	fakenode v0.0.0-00010101000000-000000000000
 	github.com/ethereum/go-ethereum v1.16.2
+	github.com/gorilla/websocket v1.5.3
 	github.com/miguelmota/go-ethereum-hdwallet v0.1.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -95,7 +98,6 @@ require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/gorilla/handlers v1.5.2 // indirect
 	github.com/gorilla/mux v1.8.1 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/grpc-ecosystem/go-grpc-middleware v1.4.0 // indirect
 	github.com/grpc-ecosystem/grpc-gateway v1.16.0 // indirect
 	github.com/gsterjov/go-libsecret v0.0.0-20161001094733-a6f4afe4910c // indirect
@@ -193,7 +195,6 @@ require (
 	google.golang.org/grpc v1.70.0 // indirect
 	google.golang.org/protobuf v1.36.5 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	gotest.tools/v3 v3.5.1 // indirect
 	nhooyr.io/websocket v1.8.7 // indirect
 	pgregory.net/rapid v1.1.0 // indirect
@@ -202,6 +203,7 @@ require (
 )
 
 replace (
+	fakenode => ../fakenode
 	github.com/btcsuite/btcd/btcec => github.com/btcsuite/btcd/btcec v0.22.1
 	github.com/cometbft/cometbft => github.com/0xPolygon/cometbft v0.2.1-polygon
 	github.com/cosmos/cosmos-sdk => github.com/0xPolygon/cosmos-sdk v0.2.5-polygon
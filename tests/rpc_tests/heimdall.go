@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// heimdallTag marks the test cases that need the Heimdall REST API, only run when
+// --heimdall-rest is set.
+const heimdallTag = "heimdall"
+
+// heimdallClient queries the REST API of a Heimdall node.
+type heimdallClient struct {
+	url    string
+	client *http.Client
+}
+
+func newHeimdallClient(url string, timeout time.Duration) *heimdallClient {
+	return &heimdallClient{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// get decodes the JSON answer of Heimdall for path into result.
+func (c *heimdallClient) get(path string, result interface{}) error {
+	response, err := c.client.Get(c.url + path)
+	if err != nil {
+		return fmt.Errorf("error querying Heimdall: %w", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("error reading Heimdall response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status %d from Heimdall %s: %s", response.StatusCode, path, body)
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("error unmarshalling Heimdall %s response: %w", path, err)
+	}
+	return nil
+}
+
+// heimdallCheckpoint is a checkpoint of Bor blocks submitted by Heimdall to L1.
+type heimdallCheckpoint struct {
+	ID         heimdallUint64 `json:"id"`
+	Proposer   string         `json:"proposer"`
+	StartBlock heimdallUint64 `json:"start_block"`
+	EndBlock   heimdallUint64 `json:"end_block"`
+	RootHash   heimdallBytes  `json:"root_hash"`
+	BorChainID string         `json:"bor_chain_id"`
+}
+
+func (c *heimdallClient) latestCheckpoint() (*heimdallCheckpoint, error) {
+	var response struct {
+		Checkpoint *heimdallCheckpoint `json:"checkpoint"`
+	}
+	if err := c.get("/checkpoints/latest", &response); err != nil {
+		return nil, err
+	}
+	if response.Checkpoint == nil {
+		return nil, fmt.Errorf("no checkpoint in Heimdall response")
+	}
+	return response.Checkpoint, nil
+}
+
+// heimdallUint64 decodes the integers of the Heimdall REST API, which are quoted.
+type heimdallUint64 uint64
+
+func (u *heimdallUint64) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseUint(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s: %w", data, err)
+	}
+	*u = heimdallUint64(value)
+	return nil
+}
+
+// heimdallBytes decodes the byte fields of the Heimdall REST API, which are base64
+// encoded, as well as 0x prefixed hex strings.
+type heimdallBytes []byte
+
+func (b *heimdallBytes) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	var decoded []byte
+	var err error
+	if strings.HasPrefix(encoded, "0x") {
+		decoded, err = hexutil.Decode(encoded)
+	} else {
+		decoded, err = base64.StdEncoding.DecodeString(encoded)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid bytes %q: %w", encoded, err)
+	}
+	*b = decoded
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"fakenode"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestLatestCheckpoint(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	chain.AddCheckpoint(fakenode.Checkpoint{ID: 3, StartBlock: 512, EndBlock: 767, RootHash: "0x" + strings.Repeat("ab", 32)})
+	heimdall := fakenode.NewHeimdall(chain)
+	defer heimdall.Close()
+
+	checkpoint, err := newHeimdallClient(heimdall.URL, time.Second).latestCheckpoint()
+	if err != nil {
+		t.Fatalf("latestCheckpoint: %v", err)
+	}
+	if checkpoint.ID != 3 || checkpoint.StartBlock != 512 || checkpoint.EndBlock != 767 || checkpoint.BorChainID != "137" {
+		t.Errorf("unexpected checkpoint %+v", checkpoint)
+	}
+	if common.BytesToHash(checkpoint.RootHash) != common.HexToHash(strings.Repeat("ab", 32)) {
+		t.Errorf("unexpected root hash %x", []byte(checkpoint.RootHash))
+	}
+}
+
+func TestCheckStateSyncEvents(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	chain.AddBlocks(20, "0x00000000000000000000000000000000000000aa")
+	contract := "0x00000000000000000000000000000000000000cc"
+	chain.AddStateSync(fakenode.StateSync{
+		ID: 1, BlockNumber: 8, TxHash: common.HexToHash("0x51").Hex(), Contract: contract,
+		Data: "0x0102", L1TxHash: common.HexToHash("0xe1").Hex(), Success: true, Executed: true,
+	})
+	chain.AddStateSync(fakenode.StateSync{
+		ID: 2, BlockNumber: 16, TxHash: common.HexToHash("0x52").Hex(), Contract: contract,
+		Data: "0x03", L1TxHash: common.HexToHash("0xe2").Hex(),
+	})
+	bor := fakenode.NewBor(chain)
+	defer bor.Close()
+	heimdall := fakenode.NewHeimdall(chain)
+	defer heimdall.Close()
+
+	client := newTestHTTPClient(bor.URL)
+	responses, err := client.Call([]Request{*NewRequest("eth_getLogs", []interface{}{stateCommittedFilter(big.NewInt(20))})})
+	if err != nil || len(responses) != 1 || responses[0].Error != nil {
+		t.Fatalf("eth_getLogs: %v %+v", err, responses)
+	}
+	logs, err := parseResponse[[]types.Log](responses[0].Result)
+	if err != nil {
+		t.Fatalf("invalid logs: %v", err)
+	}
+	heimdallClient := newHeimdallClient(heimdall.URL, time.Second)
+
+	checks := checkStateSyncEvents(client, heimdallClient, big.NewInt(137), *logs)
+	if len(checks) != 2 {
+		t.Fatalf("expected 2 checks, got %+v", checks)
+	}
+	if check := checks[0]; check.Err != nil || !check.Verified || !check.Success || check.StateID != 1 || check.Contract != common.HexToAddress(contract) {
+		t.Errorf("expected state 1 to be verified, got %+v", check)
+	}
+	// The node does not list the records executed by the second transaction
+	if check := checks[1]; !errors.Is(check.Err, errStateSyncDataNotExposed) || check.Verified || check.Success || check.StateID != 2 {
+		t.Errorf("expected state 2 to be unverifiable, got %+v", check)
+	}
+
+	for _, check := range checkStateSyncEvents(client, heimdallClient, big.NewInt(80002), *logs) {
+		if check.Err == nil || !strings.Contains(check.Err.Error(), "is for chain 137") {
+			t.Errorf("expected a chain mismatch for state %d, got %v", check.StateID, check.Err)
+		}
+	}
+
+	heimdall.InjectFault("/clerk", fakenode.Fault{Status: http.StatusInternalServerError, Times: 1})
+	checks = checkStateSyncEvents(client, heimdallClient, big.NewInt(137), *logs)
+	if checks[0].Err == nil || !strings.Contains(checks[0].Err.Error(), "unexpected HTTP status 500") {
+		t.Errorf("expected Heimdall to fail for state 1, got %v", checks[0].Err)
+	}
+	if !errors.Is(checks[1].Err, errStateSyncDataNotExposed) {
+		t.Errorf("expected state 2 to be checked once Heimdall recovers, got %v", checks[1].Err)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// borExtraVanity and borExtraSeal are the sizes of the vanity prefix and of the seal
+	// suffix that Bor keeps in the extraData of every header.
+	borExtraVanity = 32
+	borExtraSeal   = crypto.SignatureLength
+)
+
+// verifyBlockRoots recomputes the transactionsRoot, receiptsRoot and logsBloom of a block
+// fetched with full transactions, from its transactions and the receipts returned by
+// eth_getBlockReceipts, and compares them with the header.
+//
+// Bor appends its state-sync transaction and receipt to what the RPC returns for a block,
+// but they are not part of the block body, so they are left out of the recomputation.
+func verifyBlockRoots(block *RPCBlock, receipts []RPCReceipt) error {
+	if block == nil {
+		return fmt.Errorf("block not found")
+	}
+	if err := checkRequiredFields("block",
+		responseField{"hash", block.Hash != nil},
+		responseField{"transactionsRoot", block.TransactionsRoot != nil},
+		responseField{"receiptsRoot", block.ReceiptsRoot != nil},
+		responseField{"logsBloom", block.LogsBloom != nil},
+		responseField{"transactions", block.Transactions != nil},
+	); err != nil {
+		return err
+	}
+
+	// The state-sync receipt is always the last one, see handleGetStateSyncBlockReceipts
+	var stateSyncTxHash *common.Hash
+	if len(receipts) > 0 && validateStateSyncTxReceipt(&receipts[len(receipts)-1]) == nil {
+		stateSyncTxHash = receipts[len(receipts)-1].TransactionHash
+		receipts = receipts[:len(receipts)-1]
+	}
+
+	txs := make(types.Transactions, 0, len(block.Transactions))
+	for i, rawTx := range block.Transactions {
+		var rpcTx struct {
+			Hash *common.Hash `json:"hash"`
+		}
+		if err := json.Unmarshal(rawTx, &rpcTx); err != nil || rpcTx.Hash == nil {
+			return fmt.Errorf("transaction %d of block %s is not a full transaction", i, block.Hash)
+		}
+		if stateSyncTxHash != nil && *rpcTx.Hash == *stateSyncTxHash {
+			if i != len(block.Transactions)-1 {
+				return fmt.Errorf("state-sync transaction %s must be the last one of block %s, found at index %d", rpcTx.Hash, block.Hash, i)
+			}
+			continue
+		}
+
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalJSON(rawTx); err != nil {
+			return fmt.Errorf("invalid transaction %s: %w", rpcTx.Hash, err)
+		}
+		if tx.Hash() != *rpcTx.Hash {
+			return fmt.Errorf("invalid hash of transaction %d: computed %s, returned %s", i, tx.Hash(), rpcTx.Hash)
+		}
+		txs = append(txs, tx)
+	}
+
+	if len(txs) != len(receipts) {
+		return fmt.Errorf("block %s has %d transactions but %d receipts", block.Hash, len(txs), len(receipts))
+	}
+	consensusReceipts := make(types.Receipts, len(receipts))
+	for i, receipt := range receipts {
+		consensusReceipt, err := toConsensusReceipt(&receipt)
+		if err != nil {
+			return fmt.Errorf("receipt %d: %w", i, err)
+		}
+		if *receipt.TransactionHash != txs[i].Hash() {
+			return fmt.Errorf("receipt %d is for transaction %s, expected %s", i, receipt.TransactionHash, txs[i].Hash())
+		}
+		if consensusReceipt.Type != txs[i].Type() {
+			return fmt.Errorf("invalid type of receipt %d: expected %d, actual %d", i, txs[i].Type(), consensusReceipt.Type)
+		}
+		consensusReceipts[i] = consensusReceipt
+	}
+
+	if root := types.DeriveSha(txs, trie.NewStackTrie(nil)); root != *block.TransactionsRoot {
+		return fmt.Errorf("invalid transactionsRoot of block %s: computed %s, returned %s", block.Hash, root, block.TransactionsRoot)
+	}
+	if root := types.DeriveSha(consensusReceipts, trie.NewStackTrie(nil)); root != *block.ReceiptsRoot {
+		return fmt.Errorf("invalid receiptsRoot of block %s: computed %s, returned %s", block.Hash, root, block.ReceiptsRoot)
+	}
+	if bloom := types.MergeBloom(consensusReceipts); bloom != *block.LogsBloom {
+		return fmt.Errorf("invalid logsBloom of block %s: does not match the logs of its receipts", block.Hash)
+	}
+	return nil
+}
+
+// toConsensusReceipt keeps the fields of a receipt that are committed to in the
+// receiptsRoot, and checks its logsBloom against its logs.
+func toConsensusReceipt(receipt *RPCReceipt) (*types.Receipt, error) {
+	if err := checkRequiredFields("receipt",
+		responseField{"transactionHash", receipt.TransactionHash != nil},
+		responseField{"status", receipt.Status != nil},
+		responseField{"cumulativeGasUsed", receipt.CumulativeGasUsed != nil},
+		responseField{"logs", receipt.Logs != nil},
+		responseField{"logsBloom", receipt.LogsBloom != nil},
+	); err != nil {
+		return nil, err
+	}
+
+	consensusReceipt := &types.Receipt{
+		Status:            uint64(*receipt.Status),
+		CumulativeGasUsed: uint64(*receipt.CumulativeGasUsed),
+	}
+	if receipt.Type != nil {
+		consensusReceipt.Type = uint8(*receipt.Type)
+	}
+	for i := range receipt.Logs {
+		consensusReceipt.Logs = append(consensusReceipt.Logs, &receipt.Logs[i])
+	}
+	consensusReceipt.Bloom = types.CreateBloom(consensusReceipt)
+	if consensusReceipt.Bloom != *receipt.LogsBloom {
+		return nil, fmt.Errorf("invalid logsBloom of receipt %s: does not match its logs", receipt.TransactionHash)
+	}
+	return consensusReceipt, nil
+}
+
+// toConsensusHeader rebuilds a header from its RPC fields. The fields introduced by later
+// forks are only set when they are returned, as their presence changes the hash.
+func toConsensusHeader(header *RPCHeader) (*types.Header, error) {
+	if err := checkRequiredFields("header",
+		responseField{"parentHash", header.ParentHash != nil},
+		responseField{"sha3Uncles", header.UncleHash != nil},
+		responseField{"miner", header.Miner != nil},
+		responseField{"stateRoot", header.StateRoot != nil},
+		responseField{"transactionsRoot", header.TransactionsRoot != nil},
+		responseField{"receiptsRoot", header.ReceiptsRoot != nil},
+		responseField{"logsBloom", header.LogsBloom != nil},
+		responseField{"difficulty", header.Difficulty != nil},
+		responseField{"number", header.Number != nil},
+		responseField{"gasLimit", header.GasLimit != nil},
+		responseField{"gasUsed", header.GasUsed != nil},
+		responseField{"timestamp", header.Timestamp != nil},
+		responseField{"extraData", header.ExtraData != nil},
+		responseField{"mixHash", header.MixHash != nil},
+		responseField{"nonce", header.Nonce != nil},
+	); err != nil {
+		return nil, err
+	}
+
+	consensusHeader := &types.Header{
+		ParentHash:       *header.ParentHash,
+		UncleHash:        *header.UncleHash,
+		Coinbase:         *header.Miner,
+		Root:             *header.StateRoot,
+		TxHash:           *header.TransactionsRoot,
+		ReceiptHash:      *header.ReceiptsRoot,
+		Bloom:            *header.LogsBloom,
+		Difficulty:       header.Difficulty.ToInt(),
+		Number:           header.Number.ToInt(),
+		GasLimit:         uint64(*header.GasLimit),
+		GasUsed:          uint64(*header.GasUsed),
+		Time:             uint64(*header.Timestamp),
+		Extra:            *header.ExtraData,
+		MixDigest:        *header.MixHash,
+		Nonce:            *header.Nonce,
+		WithdrawalsHash:  header.WithdrawalsRoot,
+		BlobGasUsed:      (*uint64)(header.BlobGasUsed),
+		ExcessBlobGas:    (*uint64)(header.ExcessBlobGas),
+		ParentBeaconRoot: header.ParentBeaconRoot,
+		RequestsHash:     header.RequestsHash,
+	}
+	if header.BaseFee != nil {
+		consensusHeader.BaseFee = header.BaseFee.ToInt()
+	}
+	return consensusHeader, nil
+}
+
+// verifyHeaderHash checks that the hash of a header is the Keccak of its RLP encoding.
+func verifyHeaderHash(header *RPCHeader) error {
+	if header.Hash == nil {
+		return fmt.Errorf("header is missing required fields: hash")
+	}
+	consensusHeader, err := toConsensusHeader(header)
+	if err != nil {
+		return err
+	}
+	if hash := consensusHeader.Hash(); hash != *header.Hash {
+		return fmt.Errorf("invalid hash of block %s: computed %s from the header fields", header.Hash, hash)
+	}
+	return nil
+}
+
+// borSealSigner recovers the address that sealed a Bor block from the signature at the
+// end of its extraData. Bor signs the header without the signature itself, as well as
+// the baseFee since the Jaipur fork, which activated together with London.
+func borSealSigner(header *RPCHeader) (common.Address, error) {
+	consensusHeader, err := toConsensusHeader(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	extra := consensusHeader.Extra
+	if len(extra) < borExtraVanity+borExtraSeal {
+		return common.Address{}, fmt.Errorf("invalid extraData: %d bytes is too short to hold the vanity and the seal", len(extra))
+	}
+
+	sealed := []interface{}{
+		consensusHeader.ParentHash,
+		consensusHeader.UncleHash,
+		consensusHeader.Coinbase,
+		consensusHeader.Root,
+		consensusHeader.TxHash,
+		consensusHeader.ReceiptHash,
+		consensusHeader.Bloom,
+		consensusHeader.Difficulty,
+		consensusHeader.Number,
+		consensusHeader.GasLimit,
+		consensusHeader.GasUsed,
+		consensusHeader.Time,
+		extra[:len(extra)-borExtraSeal],
+		consensusHeader.MixDigest,
+		consensusHeader.Nonce,
+	}
+	if consensusHeader.BaseFee != nil {
+		sealed = append(sealed, consensusHeader.BaseFee)
+	}
+	encoded, err := rlp.EncodeToBytes(sealed)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("error encoding header: %w", err)
+	}
+
+	publicKey, err := crypto.SigToPub(crypto.Keccak256(encoded), extra[len(extra)-borExtraSeal:])
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid seal of block %s: %w", consensusHeader.Number, err)
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testBlockWithReceipts returns a block holding a legacy and a dynamic fee transaction
+// with full transactions, and the receipts eth_getBlockReceipts returns for it.
+func testBlockWithReceipts(t *testing.T) (*RPCBlock, []RPCReceipt) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainID := big.NewInt(137)
+	signer := types.LatestSignerForChainID(chainID)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	txs := types.Transactions{
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &to, Gas: 21000, GasPrice: big.NewInt(30_000_000_000), Value: big.NewInt(1)}),
+		types.MustSignNewTx(key, signer, &types.DynamicFeeTx{ChainID: chainID, Nonce: 1, To: &to, Gas: 50000, GasFeeCap: big.NewInt(60_000_000_000), GasTipCap: big.NewInt(30_000_000_000), Data: []byte{0x01}}),
+	}
+
+	logs := [][]*types.Log{
+		nil,
+		{{Address: to, Topics: []common.Hash{crypto.Keccak256Hash([]byte("Event()"))}, Data: []byte{0x02}}},
+	}
+	var consensusReceipts types.Receipts
+	var receipts []RPCReceipt
+	cumulativeGasUsed := uint64(0)
+	for i, tx := range txs {
+		cumulativeGasUsed += tx.Gas()
+		receipt := &types.Receipt{Type: tx.Type(), Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: cumulativeGasUsed, Logs: logs[i]}
+		receipt.Bloom = types.CreateBloom(receipt)
+		consensusReceipts = append(consensusReceipts, receipt)
+
+		rpcLogs := []types.Log{}
+		for _, log := range logs[i] {
+			rpcLogs = append(rpcLogs, *log)
+		}
+		receipts = append(receipts, RPCReceipt{
+			Type:              (*hexutil.Uint64)(ptr(uint64(tx.Type()))),
+			TransactionHash:   ptr(tx.Hash()),
+			CumulativeGasUsed: (*hexutil.Uint64)(ptr(cumulativeGasUsed)),
+			Logs:              rpcLogs,
+			LogsBloom:         ptr(receipt.Bloom),
+			Status:            (*hexutil.Uint64)(ptr(receipt.Status)),
+		})
+	}
+
+	block := &RPCBlock{RPCHeader: RPCHeader{
+		Hash:             ptr(common.HexToHash("0xb10c")),
+		TransactionsRoot: ptr(types.DeriveSha(txs, trie.NewStackTrie(nil))),
+		ReceiptsRoot:     ptr(types.DeriveSha(consensusReceipts, trie.NewStackTrie(nil))),
+		LogsBloom:        ptr(types.MergeBloom(consensusReceipts)),
+	}}
+	for _, tx := range txs {
+		raw, err := tx.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.Transactions = append(block.Transactions, raw)
+	}
+	return block, receipts
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// appendStateSync appends the state-sync transaction and receipt Bor adds to a block.
+func appendStateSync(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+	txHash := common.HexToHash("0x5afe")
+	block.Transactions = append(block.Transactions, json.RawMessage(`{"hash":"`+txHash.Hex()+`"}`))
+	return append(receipts, RPCReceipt{
+		TransactionHash:   &txHash,
+		TransactionIndex:  (*hexutil.Uint64)(ptr(uint64(len(receipts)))),
+		From:              &common.Address{},
+		To:                &common.Address{},
+		CumulativeGasUsed: (*hexutil.Uint64)(ptr(uint64(0))),
+		GasUsed:           (*hexutil.Uint64)(ptr(uint64(0))),
+		EffectiveGasPrice: (*hexutil.Big)(big.NewInt(0)),
+		Logs:              []types.Log{{Address: stateReceiverAddress, Topics: []common.Hash{stateSyncLogTopic}}},
+	})
+}
+
+func TestVerifyBlockRoots(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		mutate func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt
+		err    string
+	}{
+		{"valid", nil, ""},
+		{"state-sync transaction left out", appendStateSync, ""},
+		{"state-sync transaction not last", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			receipts = appendStateSync(block, receipts)
+			last := len(block.Transactions) - 1
+			block.Transactions[0], block.Transactions[last] = block.Transactions[last], block.Transactions[0]
+			return receipts
+		}, "must be the last one"},
+		{"transaction hashes only", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			block.Transactions[1] = json.RawMessage(`"` + receipts[1].TransactionHash.Hex() + `"`)
+			return receipts
+		}, "transaction 1 of block 0x000000000000000000000000000000000000000000000000000000000000b10c is not a full transaction"},
+		{"transaction hash", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			block.Transactions[0] = json.RawMessage(strings.Replace(string(block.Transactions[0]), receipts[0].TransactionHash.Hex(), receipts[1].TransactionHash.Hex(), 1))
+			return receipts
+		}, "invalid hash of transaction 0"},
+		{"missing receipt", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			return receipts[:1]
+		}, "has 2 transactions but 1 receipts"},
+		{"receipts out of order", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			return []RPCReceipt{receipts[1], receipts[0]}
+		}, "receipt 0 is for transaction"},
+		{"receipt type", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			receipts[1].Type = (*hexutil.Uint64)(ptr(uint64(types.AccessListTxType)))
+			return receipts
+		}, "invalid type of receipt 1: expected 2, actual 1"},
+		{"receipt logsBloom", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			receipts[1].LogsBloom = &types.Bloom{}
+			return receipts
+		}, "invalid logsBloom of receipt"},
+		{"receipt status", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			receipts[0].Status = (*hexutil.Uint64)(ptr(uint64(types.ReceiptStatusFailed)))
+			return receipts
+		}, "invalid receiptsRoot"},
+		{"transactionsRoot", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			block.TransactionsRoot = &types.EmptyTxsHash
+			return receipts
+		}, "invalid transactionsRoot"},
+		{"logsBloom", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			block.LogsBloom = &types.Bloom{}
+			return receipts
+		}, "invalid logsBloom of block"},
+		{"missing fields", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			block.ReceiptsRoot = nil
+			receipts[0].Status = nil
+			return receipts
+		}, "block is missing required fields: receiptsRoot"},
+		{"missing receipt fields", func(block *RPCBlock, receipts []RPCReceipt) []RPCReceipt {
+			receipts[0].Status = nil
+			return receipts
+		}, "receipt 0: receipt is missing required fields: status"},
+	} {
+		block, receipts := testBlockWithReceipts(t)
+		if tt.mutate != nil {
+			receipts = tt.mutate(block, receipts)
+		}
+		err := verifyBlockRoots(block, receipts)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+// rpcHeader returns header as eth_getHeaderByNumber returns it.
+func rpcHeader(t *testing.T, header *types.Header) *RPCHeader {
+	t.Helper()
+	raw, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rpcHeader RPCHeader
+	if err := json.Unmarshal(raw, &rpcHeader); err != nil {
+		t.Fatal(err)
+	}
+	return &rpcHeader
+}
+
+// sealedHeader returns a Bor header sealed by key. Bor signs the Keccak of the header
+// encoding without the seal, which is the hash of the same header with its extraData
+// cut before the seal as long as baseFee is its only optional field.
+func sealedHeader(t *testing.T, key *ecdsa.PrivateKey, baseFee *big.Int) *types.Header {
+	t.Helper()
+	header := &types.Header{
+		ParentHash:  common.HexToHash("0x01"),
+		UncleHash:   types.EmptyUncleHash,
+		Root:        common.HexToHash("0x02"),
+		TxHash:      types.EmptyTxsHash,
+		ReceiptHash: types.EmptyReceiptsHash,
+		Difficulty:  big.NewInt(7),
+		Number:      big.NewInt(1000),
+		GasLimit:    30_000_000,
+		Time:        1_700_000_000,
+		Extra:       make([]byte, borExtraVanity),
+		BaseFee:     baseFee,
+	}
+	signature, err := crypto.Sign(header.Hash().Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.Extra = append(header.Extra, signature...)
+	return header
+}
+
+func TestVerifyHeaderHash(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancun := sealedHeader(t, key, big.NewInt(7))
+	cancun.WithdrawalsHash = &types.EmptyWithdrawalsHash
+	cancun.BlobGasUsed = ptr(uint64(0))
+	cancun.ExcessBlobGas = ptr(uint64(0))
+	cancun.ParentBeaconRoot = &common.Hash{}
+
+	for _, tt := range []struct {
+		name   string
+		header *types.Header
+		mutate func(*RPCHeader)
+		err    string
+	}{
+		{"pre London", sealedHeader(t, key, nil), nil, ""},
+		{"London", sealedHeader(t, key, big.NewInt(7)), nil, ""},
+		{"Cancun", cancun, nil, ""},
+		{"field", sealedHeader(t, key, big.NewInt(7)), func(h *RPCHeader) { h.GasUsed = (*hexutil.Uint64)(ptr(uint64(1))) }, "computed"},
+		{"left out fork field", cancun, func(h *RPCHeader) { h.ParentBeaconRoot = nil }, "computed"},
+		{"missing hash", sealedHeader(t, key, nil), func(h *RPCHeader) { h.Hash = nil }, "header is missing required fields: hash"},
+		{"missing fields", sealedHeader(t, key, nil), func(h *RPCHeader) { h.MixHash, h.Nonce = nil, nil }, "header is missing required fields: mixHash, nonce"},
+	} {
+		header := rpcHeader(t, tt.header)
+		if tt.mutate != nil {
+			tt.mutate(header)
+		}
+		err := verifyHeaderHash(header)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestBorSealSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	for _, baseFee := range []*big.Int{nil, big.NewInt(7)} {
+		header := rpcHeader(t, sealedHeader(t, key, baseFee))
+		if address, err := borSealSigner(header); err != nil || address != signer {
+			t.Errorf("baseFee %v: expected signer %s, got %s (%v)", baseFee, signer, address, err)
+		}
+
+		// Any other field changes the signer
+		header.GasUsed = (*hexutil.Uint64)(ptr(uint64(1)))
+		if address, err := borSealSigner(header); err == nil && address == signer {
+			t.Errorf("baseFee %v: expected another signer once gasUsed changed", baseFee)
+		}
+	}
+
+	header := rpcHeader(t, sealedHeader(t, key, nil))
+	*header.ExtraData = (*header.ExtraData)[:borExtraVanity]
+	if _, err := borSealSigner(header); err == nil || !strings.Contains(err.Error(), "too short") {
+		t.Errorf("expected an error for extraData without seal, got %v", err)
+	}
+	header = rpcHeader(t, sealedHeader(t, key, nil))
+	(*header.ExtraData)[len(*header.ExtraData)-1] = 42
+	if _, err := borSealSigner(header); err == nil || !strings.Contains(err.Error(), "invalid seal of block 1000") {
+		t.Errorf("expected an error for an invalid recovery id, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// methodSideEffect is what calling a method does to the node besides answering.
+type methodSideEffect int
+
+const (
+	// changesNodeState methods install or consume node state, e.g. a filter, so that
+	// their result depends on previous calls.
+	changesNodeState methodSideEffect = iota + 1
+	// sendsTransaction methods submit a transaction, tagged "write".
+	sendsTransaction
+)
+
+// stateChangingMethods lists the methods that change node state, or whose result
+// depends on previous calls, and so must never be re-issued.
+var stateChangingMethods = map[string]methodSideEffect{
+	"eth_sendRawTransaction":          sendsTransaction,
+	"eth_sendTransaction":             sendsTransaction,
+	"eth_newFilter":                   changesNodeState,
+	"eth_newBlockFilter":              changesNodeState,
+	"eth_newPendingTransactionFilter": changesNodeState,
+	"eth_getFilterChanges":            changesNodeState,
+	"eth_uninstallFilter":             changesNodeState,
+	"eth_subscribe":                   changesNodeState,
+	"eth_unsubscribe":                 changesNodeState,
+}
+
+// isReadOnlyMethod reports whether calling method has no side effects on the node.
+func isReadOnlyMethod(method string) bool {
+	return stateChangingMethods[method] == 0
+}
+
+// isWriteMethod reports whether calling method sends a transaction.
+func isWriteMethod(method string) bool {
+	return stateChangingMethods[method] == sendsTransaction
+}
+
+// MethodLatency holds the latency distribution of repeated calls to one JSON-RPC method.
+type MethodLatency struct {
+	Method  string
+	Samples int
+	Errors  int
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// measureLatencies re-issues the request of every test case that passed and only calls
+// a read-only method, repeat times each, and returns latency percentiles per method.
+// Negative test cases are left out, their errors would count against healthy methods.
+func measureLatencies(client RPCClient, results []TestResult, repeat int) []MethodLatency {
+	samples := make(map[string][]time.Duration)
+	errorCount := make(map[string]int)
+	for _, result := range results {
+		if result.Status != TestStatusPassed || result.ExpectsError || result.Req == nil || result.Req.Raw != "" || !isReadOnlyMethod(result.Req.Method) {
+			continue
+		}
+
+		method := result.Req.Method
+		for i := 0; i < repeat; i++ {
+			timeStart := time.Now()
+			responses, err := client.Call([]Request{*result.Req})
+			elapsed := time.Since(timeStart)
+			if err != nil || len(responses) != 1 || responses[0].Error != nil {
+				errorCount[method]++
+				continue
+			}
+			samples[method] = append(samples[method], elapsed)
+		}
+	}
+
+	methods := make([]string, 0, len(samples))
+	for method := range samples {
+		methods = append(methods, method)
+	}
+	for method := range errorCount {
+		if _, ok := samples[method]; !ok {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+
+	latencies := make([]MethodLatency, 0, len(methods))
+	for _, method := range methods {
+		durations := samples[method]
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		latency := MethodLatency{
+			Method:  method,
+			Samples: len(durations),
+			Errors:  errorCount[method],
+		}
+		if len(durations) > 0 {
+			latency.P50 = percentile(durations, 50)
+			latency.P95 = percentile(durations, 95)
+			latency.P99 = percentile(durations, 99)
+			latency.Max = durations[len(durations)-1]
+		}
+		latencies = append(latencies, latency)
+	}
+
+	return latencies
+}
+
+// percentile returns the p-th percentile of sorted using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+func printTimings(summary RunSummary) {
+	fmt.Printf("\n⏱️  Batch Timings:\n")
+	for _, batch := range summary.Batches {
+		fmt.Printf("  batch %d (%d tests): %s (rpc call: %s)\n", batch.Index, batch.Size, batch.Duration, batch.CallDuration)
+	}
+
+	fmt.Printf("\n⏱️  Test Case Timings:\n")
+	for _, result := range summary.Results {
+		fmt.Printf("  [batch %d] %s: %s\n", result.Batch, result.Key, result.Duration)
+	}
+}
+
+func printLatencies(latencies []MethodLatency) {
+	fmt.Printf("\n📊  Latency per Method (%d repetitions):\n", *repeat)
+	for _, latency := range latencies {
+		fmt.Printf("  %s: p50=%s p95=%s p99=%s max=%s (samples: %d, errors: %d)\n",
+			latency.Method, latency.P50, latency.P95, latency.P99, latency.Max, latency.Samples, latency.Errors)
+	}
+}
@@ -10,11 +10,14 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"math/big"
-	"math/rand"
 	"net/http"
 	"os"
+	"reflect"
+	"regexp"
 	testcontract "rpc-tests/contracts"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -34,6 +37,9 @@ type Request struct {
 	Method  string      `json:"method"`
 	Params  interface{} `json:"params"`
 	ID      int         `json:"id"`
+	// Raw, when set, is sent on its own as the request body instead of the other fields,
+	// e.g. to send malformed JSON.
+	Raw string `json:"raw,omitempty"`
 }
 
 // Response represents the JSON-RPC response payload.
@@ -46,8 +52,9 @@ type Response struct {
 
 // RPCError represents an error in a JSON-RPC response.
 type RPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
 }
 
 type ResponseMap struct {
@@ -55,33 +62,91 @@ type ResponseMap struct {
 	mostRecentBlockNumber                  *big.Int
 	mostRecentBlockHash                    common.Hash
 	mostRecentBlockParentHash              common.Hash
+	mostRecentBlockStateRoot               common.Hash
+	mostRecentBlock                        *RPCBlock
+	mostRecentBlockSigner                  common.Address
+	mostRecentParentBlockSigner            common.Address
 	currentProposerAddress                 common.Address
 	account                                Account
 	gasPrice                               *big.Int
+	maxPriorityFeePerGas                   *big.Int
+	nextBaseFee                            *big.Int
+	accessList                             types.AccessList
+	accessListGasUsed                      uint64
 	stateSyncTxHash                        common.Hash
 	stateSyncBlockNumber                   *big.Int
 	stateSyncBlockHash                     common.Hash
 	stateSyncTxIndex                       int
 	stateSyncExpectedBlockTransactionCount int
+	stateSyncBlockReceipts                 []RPCReceipt
 	expectedGasToCreateTransaction         *big.Int
 	expectedValueToStoreInContract         *big.Int
 	expectedSlot0Value                     *big.Int
 	expectedKeyToStoreInContract           string
 	pushedTxHash                           common.Hash
+	pushedTxSentAt                         time.Time
 	pushedTxBlockNumber                    *big.Int
 	pushedTxBlockHash                      common.Hash
+	pushedTxBlockStateRoot                 common.Hash
 	pushedTxTransactionIndex               *big.Int
 	pushedTxDeployedContractAddress        common.Address
 	expectedRawTx                          string
 	pushedTxDeployedContractRuntimeCode    *[]byte
 	filterId                               string
 	blockFilterId                          string
+	fillTransactionNonce                   uint64
+	subscriber                             Subscriber
+	newHeadsSubscriptionId                 string
+	logsSubscriptionId                     string
+	pendingTxSubscriptionId                string
+	subscriptionTxHash                     common.Hash
+	subscriptionTxContractAddress          common.Address
+	subscriptionTxBlockHash                common.Hash
+	dynamicFeeTx                           *types.Transaction
+	dynamicFeeTxSentAt                     time.Time
+	dynamicFeeTxBlockNumber                *big.Int
+	dynamicFeeTxEffectiveGasPrice          *big.Int
+	accessListTx                           *types.Transaction
+	accessListTxSentAt                     time.Time
+	accessListTxEffectiveGasPrice          *big.Int
+	client                                 RPCClient
+	inclusions                             []TransactionInclusion
+	variables                              map[string]interface{}
+	schemas                                *schemaValidator
+	heimdall                               *heimdallClient
+	heimdallCheckpoint                     *heimdallCheckpoint
+	stateSyncEvents                        []StateSyncEventCheck
+	queuedTxNonce                          uint64
+	txPoolSender                           Account
+	txPoolGasPrice                         *big.Int
+	txPoolFundingTx                        *types.Transaction
+	txPoolFundingTxSentAt                  time.Time
+	txPoolQueuedTx                         *types.Transaction
+	txPoolQueuedTxGasPrice                 *big.Int
+	txPoolReplacementTx                    *types.Transaction
+	txPoolGapNonce                         uint64
+	txPoolGapTx                            *types.Transaction
+	txPoolGapTxSentAt                      time.Time
+	txPoolPromotedTxBlockNumber            *big.Int
+	nonces                                 *nonceManager
+	concurrencyFundingTxs                  map[common.Address]*types.Transaction
+	concurrencyFundingTxsSentAt            map[common.Address]time.Time
+	concurrencyFundedBlock                 *big.Int
+	concurrencyFirstTx                     *types.Transaction
+	concurrencyFirstTxSentAt               time.Time
+	concurrentTxs                          map[common.Address][]*types.Transaction
 }
 type Account struct {
-	key   *ecdsa.PrivateKey
-	addr  common.Address
-	nonce *big.Int
+	key  *ecdsa.PrivateKey
+	addr common.Address
 }
+
+// nextNonce returns the nonce to use for the next transaction of the account and
+// reserves it, so several transactions can be sent in the same batch.
+func (rm *ResponseMap) nextNonce() uint64 {
+	return rm.nonces.reserve(rm.account.addr)
+}
+
 type Accounts []Account
 type feeHistoryResult struct {
 	OldestBlock      *hexutil.Big     `json:"oldestBlock"`
@@ -117,24 +182,191 @@ type RPCTransaction struct {
 	YParity             *hexutil.Uint64   `json:"yParity,omitempty"`
 }
 
+// RPCHeader is a block header as returned by the node. Every field is a pointer so
+// that a field left out of the response can be told apart from a zero value.
+type RPCHeader struct {
+	Hash             *common.Hash      `json:"hash"`
+	ParentHash       *common.Hash      `json:"parentHash"`
+	UncleHash        *common.Hash      `json:"sha3Uncles"`
+	Miner            *common.Address   `json:"miner"`
+	StateRoot        *common.Hash      `json:"stateRoot"`
+	TransactionsRoot *common.Hash      `json:"transactionsRoot"`
+	ReceiptsRoot     *common.Hash      `json:"receiptsRoot"`
+	LogsBloom        *types.Bloom      `json:"logsBloom"`
+	Difficulty       *hexutil.Big      `json:"difficulty"`
+	Number           *hexutil.Big      `json:"number"`
+	GasLimit         *hexutil.Uint64   `json:"gasLimit"`
+	GasUsed          *hexutil.Uint64   `json:"gasUsed"`
+	Timestamp        *hexutil.Uint64   `json:"timestamp"`
+	ExtraData        *hexutil.Bytes    `json:"extraData"`
+	MixHash          *common.Hash      `json:"mixHash"`
+	Nonce            *types.BlockNonce `json:"nonce"`
+	BaseFee          *hexutil.Big      `json:"baseFeePerGas"`
+	WithdrawalsRoot  *common.Hash      `json:"withdrawalsRoot"`
+	BlobGasUsed      *hexutil.Uint64   `json:"blobGasUsed"`
+	ExcessBlobGas    *hexutil.Uint64   `json:"excessBlobGas"`
+	ParentBeaconRoot *common.Hash      `json:"parentBeaconBlockRoot"`
+	RequestsHash     *common.Hash      `json:"requestsHash"`
+}
+
+// RPCBlock is a block as returned by eth_getBlockBy*. Transactions holds either hashes
+// or full transactions depending on the request.
+type RPCBlock struct {
+	RPCHeader
+	Transactions []json.RawMessage `json:"transactions"`
+	Uncles       []common.Hash     `json:"uncles"`
+}
+
+// RPCReceipt is a transaction receipt as returned by the node, with the same pointer
+// convention as RPCHeader.
+type RPCReceipt struct {
+	Type              *hexutil.Uint64 `json:"type"`
+	TransactionHash   *common.Hash    `json:"transactionHash"`
+	TransactionIndex  *hexutil.Uint64 `json:"transactionIndex"`
+	BlockHash         *common.Hash    `json:"blockHash"`
+	BlockNumber       *hexutil.Big    `json:"blockNumber"`
+	From              *common.Address `json:"from"`
+	To                *common.Address `json:"to"`
+	CumulativeGasUsed *hexutil.Uint64 `json:"cumulativeGasUsed"`
+	GasUsed           *hexutil.Uint64 `json:"gasUsed"`
+	EffectiveGasPrice *hexutil.Big    `json:"effectiveGasPrice"`
+	ContractAddress   *common.Address `json:"contractAddress"`
+	Logs              []types.Log     `json:"logs"`
+	LogsBloom         *types.Bloom    `json:"logsBloom"`
+	Status            *hexutil.Uint64 `json:"status"`
+}
+
+// responseField names a field of a decoded response and whether the node set it.
+type responseField struct {
+	name    string
+	present bool
+}
+
+// checkRequiredFields returns an error naming every required field missing from a
+// response, or nil when all of them are set.
+func checkRequiredFields(object string, fields ...responseField) error {
+	var missing []string
+	for _, field := range fields {
+		if !field.present {
+			missing = append(missing, field.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s is missing required fields: %s", object, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// TestCase describes a single RPC check. Requires lists the ResponseMap fields read by
+// PrepareRequest and Produces the ones set by HandleResponse; they are used to schedule
+// the test case after every test case it depends on. Tags are added to the ones derived
+// from the key to select test cases with --tags and --skip-tags. Method is the called
+// method, only needed when the key does not name it. When ExpectError is set the test
+// case passes only if the node answers with that error, and HandleResponse, if any, can
+// check the error further.
 type TestCase struct {
 	Key            string
+	Method         string
+	Requires       []string
+	Produces       []string
+	Tags           []string
+	ExpectError    *ExpectedError
 	PrepareRequest func(*ResponseMap) (*Request, error)
 	HandleResponse func(*ResponseMap, Response) error
 }
 
+// ExpectedError is the JSON-RPC error a negative test case expects instead of a result.
+// Message is a regular expression the error message must match, any message matches
+// when it is empty.
+type ExpectedError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExpectedError) check(actual *RPCError) error {
+	if actual == nil {
+		return fmt.Errorf("expected error code %d, got a result", e.Code)
+	}
+	if actual.Code != e.Code {
+		return fmt.Errorf("expected error code %d, got %d (message: %s)", e.Code, actual.Code, actual.Message)
+	}
+	if e.Message != "" {
+		matches, err := regexp.MatchString(e.Message, actual.Message)
+		if err != nil {
+			return fmt.Errorf("invalid expected error message: %w", err)
+		}
+		if !matches {
+			return fmt.Errorf("expected error message matching %q, got %q", e.Message, actual.Message)
+		}
+	}
+	return nil
+}
+
 type BatchTestCase []TestCase
-type FailedTestCase struct {
-	Err error
-	Key string
-	Req Request
-	Res Response
+
+type TestStatus string
+
+const (
+	TestStatusPassed  TestStatus = "passed"
+	TestStatusFailed  TestStatus = "failed"
+	TestStatusSkipped TestStatus = "skipped"
+	// TestStatusErrored means the request never got a valid answer, e.g. the call
+	// failed at the transport level or the response for its id is missing.
+	TestStatusErrored TestStatus = "errored"
+)
+
+// TestResult holds the outcome of a single test case run.
+type TestResult struct {
+	Key    string
+	Batch  int
+	Status TestStatus
+	// ExpectsError is set for the negative test cases, which pass on a JSON-RPC error.
+	ExpectsError bool
+	Err          error
+	Req          *Request
+	Res          *Response
+	Duration     time.Duration
+	Diffs        []FieldDiff
+}
+
+// BatchResult holds the timings of a single batch run.
+type BatchResult struct {
+	Index        int
+	Size         int
+	Duration     time.Duration
+	CallDuration time.Duration
+}
+
+// RunSummary holds everything collected during a run, used to print and write reports.
+type RunSummary struct {
+	ClientVersion   string
+	Seed            int64
+	StartedAt       time.Time
+	Duration        time.Duration
+	Results         []TestResult
+	Batches         []BatchResult
+	Latencies       []MethodLatency
+	Inclusions      []TransactionInclusion
+	StateSyncEvents []StateSyncEventCheck
+}
+
+// headNotification holds the fields of a newHeads notification the tests rely on.
+type headNotification struct {
+	Hash   common.Hash  `json:"hash"`
+	Number *hexutil.Big `json:"number"`
 }
+
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
 	Tx  *types.Transaction `json:"tx"`
 }
 
+// blockFees holds the block fields needed to check the effective gas price of a dynamic fee tx.
+type blockFees struct {
+	BaseFee      *hexutil.Big  `json:"baseFeePerGas"`
+	Transactions []common.Hash `json:"transactions"`
+}
+
 type accessListResult struct {
 	Accesslist *types.AccessList `json:"accessList"`
 	Error      string            `json:"error,omitempty"`
@@ -158,171 +390,347 @@ type accountResult struct {
 }
 
 var (
-	rpcURL      = flag.String("rpc-url", "", "RPC Url to be tested")
-	mnemonic    = flag.String("mnemonic", "", "mnemonic to be used on transactions")
-	privKey     = flag.String("priv-key", "", "privKey to be used on transactions")
-	filterTests = flag.Bool("filter-test", false, "True if want to include filter tests (recommended just when there is no load balancer)")
-	logReqRes   = flag.Bool("log-req-res", false, "True if want to log requests and responses)")
+	rpcURL          = flag.String("rpc-url", "", "RPC Url to be tested")
+	mnemonic        = flag.String("mnemonic", "", "mnemonic to be used on transactions")
+	privKey         = flag.String("priv-key", "", "privKey to be used on transactions")
+	filterTests     = flag.Bool("filter-test", false, "True if want to include filter tests (recommended just when there is no load balancer)")
+	logReqRes       = flag.Bool("log-req-res", false, "True if want to log requests and responses)")
+	reportDir       = flag.String("report", "", "Directory to write JUnit XML and JSON reports to (disabled when empty)")
+	timings         = flag.Bool("timings", false, "True if want to print per test case and per batch timings")
+	repeat          = flag.Int("repeat", 0, "Number of times to re-issue each read-only request to measure latency percentiles per method (disabled when 0)")
+	baselineURL     = flag.String("baseline-rpc-url", "", "RPC Url of a baseline node to compare read-only responses against (disabled when empty)")
+	diffIgnore      = flag.String("diff-ignore", "", "Comma separated list of methods (\"method\") or fields (\"method:result.path.*.field\") to ignore when comparing with the baseline")
+	rpcTimeout      = flag.Duration("rpc-timeout", 60*time.Second, "Timeout of a single RPC call, including reading the whole response")
+	rpcRetries      = flag.Int("rpc-retries", 3, "Number of times a failed call made only of read-only methods is retried on transport errors")
+	declarative     = flag.String("test-cases", "", "Comma separated list of YAML/JSON files or directories with declarative test cases to run alongside the built-in ones")
+	rpcBackoff      = flag.Duration("rpc-retry-backoff", 500*time.Millisecond, "Delay before the first retry of a failed call, doubled on every further retry")
+	runRegex        = flag.String("run", "", "Only run the test cases whose key matches this regular expression, plus the ones they depend on")
+	skipRegex       = flag.String("skip", "", "Skip the test cases whose key matches this regular expression, and the ones depending on them")
+	tags            = flag.String("tags", "", "Comma separated list of tags (e.g. bor,state-sync); only run the test cases having one of them, plus the ones they depend on")
+	skipTags        = flag.String("skip-tags", "", "Comma separated list of tags; skip the test cases having one of them, and the ones depending on them")
+	readOnly        = flag.Bool("read-only", false, "Skip every test case that sends a transaction, and the ones depending on them, so shared RPC endpoints can be tested safely")
+	listTests       = flag.Bool("list", false, "Print the selected test cases with their tags and exit")
+	checkSchema     = flag.Bool("schema", true, "Validate every result against the OpenRPC schema of its method (execution-apis for eth_*, Bor's for bor_*)")
+	openRPCSpec     = flag.String("openrpc-spec", "", "Path to an execution-apis openrpc.json to validate eth_* results against instead of the embedded one")
+	coverageMode    = flag.Bool("coverage", false, "Print which eth, bor, debug, txpool, net and web3 methods served by the node the selected test cases call, then exit")
+	heimdallREST    = flag.String("heimdall-rest", "", "Heimdall REST API URL to cross-check checkpoint root hashes and state sync events against (disabled when empty)")
+	stateSyncBlocks = flag.Uint64("state-sync-blocks", 30000, "Number of blocks before the most recent one in which state sync events are searched")
+	recordDir       = flag.String("record", "", "Directory to record every JSON-RPC call and its response to, as a cassette to replay with --replay (disabled when empty)")
+	replayDir       = flag.String("replay", "", "Directory of a cassette recorded with --record to serve the responses of, in place of --rpc-url")
+	seed            = flag.Int64("seed", 0, "Seed of the random values of the run, like generated keys, printed in the report to replay a run with identical payloads (random when 0)")
+	senders         = flag.Int("senders", 1, "Number of sender accounts derived from --mnemonic; the ones after the first send transactions concurrently in the concurrency scenario")
 )
 
 func main() {
 	flag.Parse()
-	if *mnemonic == "" && *privKey == "" {
+
+	selection := testSelection{
+		tags:     parseTags(*tags),
+		skipTags: parseTags(*skipTags),
+		readOnly: *readOnly,
+	}
+	for _, pattern := range []struct {
+		flag  string
+		value string
+		regex **regexp.Regexp
+	}{{"run", *runRegex, &selection.run}, {"skip", *skipRegex, &selection.skip}} {
+		if pattern.value == "" {
+			continue
+		}
+		regex, err := regexp.Compile(pattern.value)
+		if err != nil {
+			fmt.Printf("Invalid --%s flag: %v\n", pattern.flag, err)
+			os.Exit(1)
+			return
+		}
+		*pattern.regex = regex
+	}
+
+	if *replayDir != "" {
+		if *rpcURL != "" || *recordDir != "" {
+			fmt.Println("--replay serves the recorded responses in place of --rpc-url, and cannot be combined with it or --record")
+			os.Exit(1)
+			return
+		}
+		// Concurrent senders allocate request ids in whichever order they run
+		if *senders > 1 {
+			fmt.Println("--replay cannot replay the calls of concurrent --senders")
+			os.Exit(1)
+			return
+		}
+		// Only the JSON-RPC calls are recorded, the Heimdall checks would query the live API
+		if *heimdallREST != "" {
+			fmt.Println("--replay cannot replay the Heimdall REST calls of --heimdall-rest")
+			os.Exit(1)
+			return
+		}
+		server, err := newReplayServer(*replayDir)
+		if err != nil {
+			fmt.Printf("Error while loading cassette %s: %v\n", *replayDir, err)
+			os.Exit(1)
+			return
+		}
+		defer server.Close()
+		// The payloads, hence the requests, only match the recorded ones with the same seed
+		if *seed != 0 && *seed != server.Manifest.Seed {
+			fmt.Printf("Cassette %s was recorded with --seed %d\n", *replayDir, server.Manifest.Seed)
+			os.Exit(1)
+			return
+		}
+		*seed = server.Manifest.Seed
+		*rpcURL = server.URL
+		clock = server.replayClock()
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	randomness = newRunRandomness(*seed)
+
+	builtinTestCases := slices.Concat(testCases, negativeTestCases, txPoolTestCases, concurrencyTestCases(*senders))
+	availableTestCases := make([]TestCase, 0, len(builtinTestCases))
+	for _, testCase := range builtinTestCases {
+		if !*filterTests && isFilterChangesTestCase(testCase) {
+			continue
+		}
+		// Subscription test cases need the notifications only a WebSocket connection receives
+		if !isWebSocketURL(*rpcURL) && isSubscriptionTestCase(testCase) {
+			continue
+		}
+		if *heimdallREST == "" && isHeimdallTestCase(testCase) {
+			continue
+		}
+		availableTestCases = append(availableTestCases, testCase)
+	}
+
+	if *declarative != "" {
+		declaredTestCases, err := loadDeclarativeTestCases(*declarative)
+		if err == nil {
+			err = checkDeclarativeTestCases(builtinTestCases, declaredTestCases)
+		}
+		if err != nil {
+			fmt.Printf("Invalid declarative test cases: %v\n", err)
+			os.Exit(1)
+			return
+		}
+		availableTestCases = append(availableTestCases, declaredTestCases...)
+	}
+
+	selectedTestCases, unsatisfiedTestCases := selectTestCases(availableTestCases, selection)
+	for _, testCase := range unsatisfiedTestCases {
+		fmt.Printf("⚠️  Not running %q: it depends on test cases that are skipped\n", testCase.Key)
+	}
+	if *listTests {
+		for _, testCase := range selectedTestCases {
+			fmt.Printf("%s [%s]\n", testCase.Key, strings.Join(testCaseTags(testCase), ", "))
+		}
+		return
+	}
+	if len(selectedTestCases) == 0 {
+		fmt.Println("No test case selected")
+		os.Exit(1)
+		return
+	}
+
+	if *mnemonic == "" && *privKey == "" && !*coverageMode {
 		fmt.Println("Must provide either mnemonic or privKey")
 		os.Exit(1)
 		return
 	}
+	if *senders < 1 || (*senders > 1 && *mnemonic == "") {
+		fmt.Println("Invalid senders flag: at least 1, and several senders can only be derived from a mnemonic")
+		os.Exit(1)
+		return
+	}
 	if *rpcURL == "" {
 		fmt.Println("Invalid rpcURL flag")
 		os.Exit(1)
 		return
 	}
 
-	// Ethereum node RPC endpoint
-	mapTestCases := testCasesToMap(testCases)
-	rm := ResponseMap{}
-	mapRequestIdToKey := make(map[int]string)
-	var failedTestCases []FailedTestCase
+	var schemas *schemaValidator
+	if *checkSchema {
+		var err error
+		schemas, err = loadSchemaValidator(*openRPCSpec)
+		if err != nil {
+			fmt.Printf("Invalid OpenRPC schemas: %v\n", err)
+			os.Exit(1)
+			return
+		}
+	}
+
+	config := clientConfig{
+		Timeout:    *rpcTimeout,
+		MaxRetries: *rpcRetries,
+		Backoff:    *rpcBackoff,
+		MaxBackoff: 10 * time.Second,
+	}
+	client, err := newRPCClient(*rpcURL, config)
+	if err != nil {
+		fmt.Printf("Error while connecting to %s: %v\n", *rpcURL, err)
+		os.Exit(1)
+		return
+	}
+	// Notifications are not recorded, subscriptions use the connection directly
+	subscriber, _ := client.(Subscriber)
+	if *recordDir != "" {
+		manifest := cassetteManifest{Seed: *seed, RecordedAt: time.Now(), RPCURL: *rpcURL}
+		client, err = newRecordingClient(client, *recordDir, manifest)
+		if err != nil {
+			fmt.Printf("Error while recording to %s: %v\n", *recordDir, err)
+			os.Exit(1)
+			return
+		}
+	}
+	defer client.Close()
+
+	if *coverageMode {
+		modules, err := fetchModules(client)
+		if err != nil {
+			fmt.Printf("⚠️  Could not fetch rpc_modules, reporting on every namespace: %v\n", err)
+		}
+		methodCoverage := computeCoverage(selectedTestCases, modules)
+		printCoverage(methodCoverage)
+		if *reportDir != "" {
+			if err := writeCoverageReport(*reportDir, methodCoverage); err != nil {
+				fmt.Printf("Error while writing coverage report: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	rm := ResponseMap{client: client, schemas: schemas}
+	if *heimdallREST != "" {
+		rm.heimdall = newHeimdallClient(*heimdallREST, *rpcTimeout)
+	}
+	rm.subscriber = subscriber
+	var accounts Accounts
 	if *mnemonic != "" {
-		rm.account = generateAccountsUsingMnemonic(*mnemonic, 1)[0]
+		accounts = generateAccountsUsingMnemonic(*mnemonic, *senders)
 	} else {
 		acc, _ := generateAccountUsingPrivKey(*privKey)
-		rm.account = *acc
+		accounts = Accounts{*acc}
 	}
+	rm.account = accounts[0]
+	rm.nonces = newNonceManager(accounts)
 
 	rm.expectedGasToCreateTransaction = big.NewInt(360333)
 	rm.expectedValueToStoreInContract = big.NewInt(30)
 	rm.expectedKeyToStoreInContract = "key"
 	rm.expectedSlot0Value = big.NewInt(42) // first variable set on contract
 
-	// Test cases are grouped into batches when there are no dependencies between them.
-	// If one test case depends on the response of another to construct its request,
-	// it should be placed in a subsequent batch to maintain the correct order.
-	testCaseBatches := []BatchTestCase{
-		{
-			mapTestCases["bor_getAuthor (no params)"],
-			mapTestCases["bor_getCurrentProposer"],
-			mapTestCases["bor_getCurrentValidators"],
-			mapTestCases["bor_getSnapshotProposer"],
-			mapTestCases["bor_getSnapshotProposerSequence"],
-			mapTestCases["eth_blockNumber"],
-			mapTestCases["eth_chainId"],
-			mapTestCases["eth_feeHistory"],
-			mapTestCases["eth_gasPrice"],
-			mapTestCases["eth_getBalance"],
-		}, {
-			mapTestCases["eth_getTransactionCount"],
-			mapTestCases["eth_maxPriorityFeePerGas"],
-			mapTestCases["eth_syncing"],
-		},
-		{
-			mapTestCases["bor_getAuthor (by number)"],
-			mapTestCases["bor_getRootHash"],
-			mapTestCases["bor_getSigners"],
-			mapTestCases["bor_getSnapshot"],
-			mapTestCases["eth_getBlockByNumber"],
-			mapTestCases["eth_getHeaderByNumber"],
-			mapTestCases["Create Transaction Scenario: eth_estimateGas"],
-			mapTestCases["Create Transaction Scenario: eth_fillTransaction"],
-			mapTestCases["StateSyncTx Scenario: eth_getLogs"],
-		},
-		{
-			mapTestCases["bor_getAuthor (by hash)"],
-			mapTestCases["bor_getSignersAtHash"],
-			mapTestCases["bor_getSnapshotAtHash"],
-			mapTestCases["eth_getBlockByHash"],
-			mapTestCases["eth_getHeaderByHash"],
-			mapTestCases["Create Transaction Scenario: eth_sendRawTransaction"],
-			mapTestCases["Create Transaction Scenario: eth_createAccessList"],
-			mapTestCases["StateSyncTx Scenario: eth_getTransactionReceipt"],
-		},
-		{
-			mapTestCases["Create Transaction Scenario: eth_getRawTransactionByHash"],
-			mapTestCases["Create Transaction Scenario: eth_getTransactionReceipt"],
-			mapTestCases["StateSyncTx Scenario: eth_getBlockReceipts"],
-			mapTestCases["StateSyncTx Scenario: eth_getTransactionByHash"],
-			mapTestCases["StateSyncTx Scenario: eth_getTransactionByBlockHashAndIndex"],
-			mapTestCases["StateSyncTx Scenario: eth_getTransactionByBlockNumberAndIndex"],
-		},
-		{
-			mapTestCases["Create Transaction Scenario: eth_getCode"],
-			mapTestCases["Create Transaction Scenario: eth_call"],
-			mapTestCases["Create Transaction Scenario: eth_newFilter"],
-			mapTestCases["Create Transaction Scenario: eth_newBlockFilter"],
-			mapTestCases["Create Transaction Scenario: eth_getRawTransactionByBlockHashAndIndex"],
-			mapTestCases["Create Transaction Scenario: eth_getRawTransactionByBlockNumberAndIndex"],
-			mapTestCases["Create Transaction Scenario: eth_getStorageAt"],
-			mapTestCases["Create Transaction Scenario: eth_getProof"],
-			mapTestCases["StateSyncTx Scenario: eth_getBlockTransactionCountByHash"],
-			mapTestCases["StateSyncTx Scenario: eth_getBlockTransactionCountByNumber"],
-		},
-	}
-
-	if *filterTests {
-		testCaseBatches = append(testCaseBatches, BatchTestCase{
-			mapTestCases["Create Transaction Scenario: eth_getFilterChanges (from eth_newFilter)"],
-			mapTestCases["Create Transaction Scenario: eth_getFilterChanges (from eth_newBlockFilter)"],
-		})
+	// Test cases are grouped into batches according to the ResponseMap fields they
+	// require and produce, so a test case always runs after the ones it depends on.
+	testCaseBatches, err := scheduleTestCases(selectedTestCases)
+	if err != nil {
+		fmt.Printf("Invalid test case dependencies: %v\n", err)
+		os.Exit(1)
+		return
 	}
 
 	timeStart := time.Now()
-	countTestCases := 0
-	for _, testCaseBatch := range testCaseBatches {
-		// Preparing Request
-		requests := make([]Request, 0)
-		mapRequests := make(map[int]Request)
-		countTestCases += len(testCaseBatch)
-		for _, testCase := range testCaseBatch {
-			req, err := testCase.PrepareRequest(&rm)
-			if err != nil {
-				failedTestCases = append(failedTestCases, FailedTestCase{Key: testCase.Key, Err: err})
-				continue
-			}
-			if req == nil {
-				continue
-			}
-
-			mapRequestIdToKey[req.ID] = testCase.Key
-			mapRequests[req.ID] = *req
-
-			requests = append(requests, *req)
-		}
+	results, batches := runTestCases(client, testCaseBatches, &rm)
+	duration := time.Since(timeStart)
 
-		responses, err := CallEthereumRPC(requests, *rpcURL)
-		if err != nil {
-			fmt.Printf("Error while calling Ethereum RPC: %v\n", err)
+	var diffErr error
+	if *baselineURL != "" {
+		rules := parseDiffIgnoreRules(strings.Join(defaultDiffIgnoreRules, ",") + "," + *diffIgnore)
+		var baselineClient RPCClient
+		baselineClient, diffErr = newRPCClient(*baselineURL, config)
+		if diffErr == nil {
+			diffErr = compareWithBaseline(client, baselineClient, results, rules)
+			baselineClient.Close()
 		}
+	}
 
-		// Handling Response
-		for _, response := range responses {
-			key := mapRequestIdToKey[response.ID]
-			if response.Error != nil {
-				failedTestCases = append(failedTestCases, FailedTestCase{Key: key, Err: fmt.Errorf("request error; message: %s | code: %d", response.Error.Message, response.Error.Code), Req: mapRequests[response.ID], Res: response})
-				continue
-			}
-			err := mapTestCases[key].HandleResponse(&rm, response)
-			if err != nil {
-				failedTestCases = append(failedTestCases, FailedTestCase{Key: key, Err: err, Req: mapRequests[response.ID], Res: response})
-			}
-
+	summary := RunSummary{
+		Seed:            *seed,
+		StartedAt:       timeStart,
+		Duration:        duration,
+		Results:         results,
+		Batches:         batches,
+		Inclusions:      rm.inclusions,
+		StateSyncEvents: rm.stateSyncEvents,
+	}
+	if *repeat > 0 {
+		summary.Latencies = measureLatencies(client, results, *repeat)
+	}
+
+	var passedTests int
+	var failedTestCases, skippedTestCases, erroredTestCases []TestResult
+	for _, result := range results {
+		switch result.Status {
+		case TestStatusPassed:
+			passedTests++
+		case TestStatusFailed:
+			failedTestCases = append(failedTestCases, result)
+		case TestStatusSkipped:
+			skippedTestCases = append(skippedTestCases, result)
+		case TestStatusErrored:
+			erroredTestCases = append(erroredTestCases, result)
 		}
 	}
-
-	passedTests := countTestCases - len(failedTestCases)
-	duration := time.Since(timeStart)
+	countTestCases := len(results)
 
 	fmt.Println("════════════════════════════════════════")
 	fmt.Println("🚀  All Tests Executed!")
 	fmt.Printf("✅  Success: %d/%d tests passed\n", passedTests, countTestCases)
+	if len(skippedTestCases) > 0 {
+		fmt.Printf("⏭️  Skipped: %d/%d tests\n", len(skippedTestCases), countTestCases)
+	}
+	if len(erroredTestCases) > 0 {
+		fmt.Printf("💥  Errored: %d/%d tests (no valid answer)\n", len(erroredTestCases), countTestCases)
+	}
 	fmt.Printf("⌛  Duration: %s\n", duration)
+	fmt.Printf("🎲  Seed: %d (replay with --seed %d)\n", *seed, *seed)
 	fmt.Println("════════════════════════════════════════")
 
+	if diffErr != nil {
+		fmt.Printf("Error while comparing with baseline: %v\n", diffErr)
+	}
+
+	if *timings {
+		printTimings(summary)
+	}
+	if len(summary.Inclusions) > 0 {
+		printInclusions(summary.Inclusions)
+	}
+	if len(summary.StateSyncEvents) > 0 {
+		printStateSyncEvents(summary.StateSyncEvents)
+	}
+	if len(summary.Latencies) > 0 {
+		printLatencies(summary.Latencies)
+	}
+
+	if *reportDir != "" {
+		summary.ClientVersion = fetchClientVersion(client)
+		if err := writeReports(*reportDir, summary); err != nil {
+			fmt.Printf("Error while writing reports: %v\n", err)
+		} else {
+			fmt.Printf("📝  Reports written to %s\n", *reportDir)
+		}
+	}
+
+	if len(skippedTestCases) > 0 {
+		fmt.Printf("\n\n")
+		fmt.Println("⏭️ Skipped Test Cases:")
+		for _, skippedTestCase := range skippedTestCases {
+			fmt.Printf("\n  🔎 Test Case Key: %s\n", skippedTestCase.Key)
+			fmt.Printf("      ⚠️ Reason: %s\n", skippedTestCase.Err)
+		}
+	}
+
 	if len(failedTestCases) > 0 {
 		fmt.Printf("\n\n")
 		fmt.Println("❌ Failed Test Cases:")
 		for _, failedTestCase := range failedTestCases {
 			fmt.Printf("\n  🔎 Test Case Key: %s\n", failedTestCase.Key)
 			fmt.Printf("      🚫 Error: %s\n", failedTestCase.Err)
+			for _, diff := range failedTestCase.Diffs {
+				primary, _ := json.Marshal(diff.Primary)
+				baseline, _ := json.Marshal(diff.Baseline)
+				fmt.Printf("      ↔️ %s: %s (baseline: %s)\n", diff.Path, primary, baseline)
+			}
 			if *logReqRes {
 				request, _ := json.Marshal(failedTestCase.Req)
 				response, _ := json.Marshal(failedTestCase.Res)
@@ -330,69 +738,333 @@ func main() {
 				fmt.Printf("      📥 Response: %s\n", string(response))
 			}
 		}
+	}
+
+	// Errored test cases got no valid answer, e.g. a missing or duplicated response id
+	if len(erroredTestCases) > 0 {
+		fmt.Printf("\n\n")
+		fmt.Println("💥 Errored Test Cases:")
+		for _, erroredTestCase := range erroredTestCases {
+			fmt.Printf("\n  🔎 Test Case Key: %s\n", erroredTestCase.Key)
+			fmt.Printf("      🚫 Error: %s\n", erroredTestCase.Err)
+			if *logReqRes {
+				request, _ := json.Marshal(erroredTestCase.Req)
+				response, _ := json.Marshal(erroredTestCase.Res)
+				fmt.Printf("      📤 Request: %s\n", string(request))
+				fmt.Printf("      📥 Response: %s\n", string(response))
+			}
+		}
+	}
+
+	if len(failedTestCases) > 0 || len(erroredTestCases) > 0 || diffErr != nil {
 		os.Exit(1)
 	}
 }
 
-func testCasesToMap(testCases []TestCase) map[string]TestCase {
-	mapTestCases := make(map[string]TestCase)
-	for _, testCase := range testCases {
-		mapTestCases[testCase.Key] = testCase
-	}
-	return mapTestCases
+// runTestCases executes the scheduled batches in order and returns one result per test case
+// together with the timings of every batch.
+func runTestCases(client RPCClient, testCaseBatches []BatchTestCase, rm *ResponseMap) ([]TestResult, []BatchResult) {
+	var results []TestResult
+	var batches []BatchResult
+	// unavailableFields maps a ResponseMap field to the test case that failed to produce it
+	unavailableFields := make(map[string]string)
+	for batchIndex, testCaseBatch := range testCaseBatches {
+		batchResults, batch := runBatch(client, batchIndex, testCaseBatch, rm, unavailableFields)
+		results = append(results, batchResults...)
+		batches = append(batches, batch)
+	}
+	return results, batches
 }
 
-// CallEthereumRPC performs an RPC call to an Ethereum node.
-func CallEthereumRPC(reqPayload []Request, rpcURL string) ([]Response, error) {
-	// Serialize the request to JSON
-	reqBytes, err := json.Marshal(reqPayload)
-	if err != nil {
-		return nil, fmt.Errorf("error marshalling request: %w", err)
-	}
+// runBatch sends the requests of all test cases in a batch as a single JSON-RPC batch
+// call and hands every response to the test case that issued the request.
+func runBatch(client RPCClient, batchIndex int, testCaseBatch BatchTestCase, rm *ResponseMap, unavailableFields map[string]string) ([]TestResult, BatchResult) {
+	batchStart := time.Now()
+	results := make([]TestResult, len(testCaseBatch))
+	mapRequestIdToIndex := make(map[int]int)
+	var rawIndexes []int
+
+	// Preparing Request
+	requests := make([]Request, 0, len(testCaseBatch))
+	for i, testCase := range testCaseBatch {
+		result := &results[i]
+		result.Key = testCase.Key
+		result.Batch = batchIndex
+		result.ExpectsError = testCase.ExpectError != nil
+
+		if cause, ok := unavailableDependency(testCase, unavailableFields); ok {
+			result.Status = TestStatusSkipped
+			result.Err = fmt.Errorf("skipped because %q did not pass", cause)
+			markProducedFieldsUnavailable(testCase, cause, unavailableFields)
+			continue
+		}
 
-	// Encapsulate the entire HTTP request/response cycle in a closure
-	rpcResp, err := func() ([]Response, error) {
-		// Make the HTTP POST request
-		resp, err := http.Post(rpcURL, "application/json", bytes.NewBuffer(reqBytes))
+		timeStart := time.Now()
+		var req *Request
+		err := recoverPanic(func() (err error) {
+			req, err = testCase.PrepareRequest(rm)
+			return err
+		})
+		result.Duration = time.Since(timeStart)
 		if err != nil {
-			return nil, fmt.Errorf("error making RPC call: %w", err)
+			result.Status = TestStatusFailed
+			result.Err = err
+			continue
 		}
-		defer func(Body io.ReadCloser) {
-			err := Body.Close()
-			if err != nil {
-				log.Fatalf("error closing the reader: %v", err)
-			}
-		}(resp.Body)
+		if req == nil {
+			result.Status = TestStatusSkipped
+			result.Err = errors.New("no request was prepared")
+			continue
+		}
+
+		result.Req = req
+		if req.Raw != "" {
+			rawIndexes = append(rawIndexes, i)
+			continue
+		}
+		if other, ok := mapRequestIdToIndex[req.ID]; ok {
+			result.Status = TestStatusErrored
+			result.Err = fmt.Errorf("request id %d is already used by %q", req.ID, testCaseBatch[other].Key)
+			continue
+		}
+		mapRequestIdToIndex[req.ID] = i
+		requests = append(requests, *req)
+	}
+
+	var responses []Response
+	var callErr error
+	var callDuration time.Duration
+	// An empty batch is rejected by the node, so nothing is sent when every test case was skipped
+	if len(requests) > 0 {
+		timeStart := time.Now()
+		responses, callErr = client.Call(requests)
+		callDuration = time.Since(timeStart)
+		if callErr != nil {
+			fmt.Printf("Error while calling Ethereum RPC: %v\n", callErr)
+		}
+	}
 
-		// Read the response body
-		body, err := io.ReadAll(resp.Body)
+	// Handling Response
+	var unexpected []TestResult
+	responseCount := make(map[int]int)
+	for _, response := range responses {
+		responseCount[response.ID]++
+		i, ok := mapRequestIdToIndex[response.ID]
+		if !ok {
+			res := response
+			unexpected = append(unexpected, TestResult{
+				Key:    fmt.Sprintf("batch %d: response id %d", batchIndex, response.ID),
+				Batch:  batchIndex,
+				Status: TestStatusErrored,
+				Err:    fmt.Errorf("received a response with id %d which matches no request of the batch", response.ID),
+				Res:    &res,
+			})
+			continue
+		}
+		result := &results[i]
+		if responseCount[response.ID] > 1 {
+			result.Status = TestStatusErrored
+			result.Err = fmt.Errorf("received %d responses for request id %d", responseCount[response.ID], response.ID)
+			continue
+		}
+		timeStart := time.Now()
+		handleTestCaseResponse(rm, testCaseBatch[i], result, response)
+		result.Duration += callDuration + time.Since(timeStart)
+	}
+
+	// Raw requests cannot share the batch, e.g. malformed JSON would spoil every request
+	for _, i := range rawIndexes {
+		result := &results[i]
+		rawCaller, ok := client.(RawCaller)
+		if !ok {
+			result.Status = TestStatusSkipped
+			result.Err = errors.New("raw requests can only be sent over HTTP")
+			continue
+		}
+		timeStart := time.Now()
+		response, err := rawCaller.CallRaw([]byte(result.Req.Raw))
 		if err != nil {
-			return nil, fmt.Errorf("error reading response body: %w", err)
+			result.Status = TestStatusErrored
+			result.Err = err
+		} else {
+			handleTestCaseResponse(rm, testCaseBatch[i], result, *response)
 		}
+		result.Duration += time.Since(timeStart)
+	}
 
-		// Deserialize the response
-		var internalResp []Response
-		if err := json.Unmarshal(body, &internalResp); err != nil {
-			return nil, fmt.Errorf("error unmarshalling response: %w", err)
+	// Every request that was sent must have received exactly one response
+	for _, i := range mapRequestIdToIndex {
+		result := &results[i]
+		if responseCount[result.Req.ID] > 0 {
+			continue
+		}
+		result.Status = TestStatusErrored
+		if callErr != nil {
+			result.Err = fmt.Errorf("no response received: %w", callErr)
+		} else {
+			result.Err = fmt.Errorf("no response received for request id %d", result.Req.ID)
 		}
+		result.Duration += callDuration
+	}
+
+	// Anything a test case was supposed to produce is now unavailable to its dependents
+	for i, testCase := range testCaseBatch {
+		if results[i].Status != TestStatusPassed {
+			markProducedFieldsUnavailable(testCase, testCase.Key, unavailableFields)
+		}
+	}
+	results = append(results, unexpected...)
+
+	return results, BatchResult{
+		Index:        batchIndex,
+		Size:         len(testCaseBatch),
+		Duration:     time.Since(batchStart),
+		CallDuration: callDuration,
+	}
+}
+
+// handleTestCaseResponse checks the response received by a test case and hands it over
+// to the test case.
+func handleTestCaseResponse(rm *ResponseMap, testCase TestCase, result *TestResult, response Response) {
+	result.Res = &response
+
+	err := validateResponseEnvelope(response)
+	if err != nil {
+		result.Status = TestStatusErrored
+		result.Err = err
+		return
+	}
+	switch {
+	case testCase.ExpectError != nil:
+		err = testCase.ExpectError.check(response.Error)
+	case response.Error != nil:
+		err = fmt.Errorf("request error; message: %s | code: %d", response.Error.Message, response.Error.Code)
+	default:
+		err = rm.schemas.validateResult(result.Req.Method, response.Result)
+	}
+	if err == nil && testCase.HandleResponse != nil {
+		err = recoverPanic(func() error { return testCase.HandleResponse(rm, response) })
+	}
+	if err != nil {
+		result.Status = TestStatusFailed
+		result.Err = err
+		return
+	}
+	result.Status = TestStatusPassed
+}
 
-		return internalResp, nil
+// recoverPanic runs fn and turns a panic into an error, so that an unexpected response
+// fails its own test case instead of aborting the run.
+func recoverPanic(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
 	}()
+	return fn()
+}
+
+// validateResponseEnvelope checks that a response is a valid JSON-RPC 2.0 answer, i.e.
+// that it carries either a result or an error.
+func validateResponseEnvelope(response Response) error {
+	if response.JsonRPC != "2.0" {
+		return fmt.Errorf("invalid jsonrpc version %q in response", response.JsonRPC)
+	}
+	if response.Error == nil && response.Result == nil {
+		return errors.New("response has neither a result nor an error")
+	}
+	if response.Error != nil && response.Result != nil {
+		return errors.New("response has both a result and an error")
+	}
+	return nil
+}
+
+// isSubscriptionTestCase reports whether the test case relies on eth_subscribe
+// notifications, which are only available over WebSocket.
+func isSubscriptionTestCase(testCase TestCase) bool {
+	return strings.HasPrefix(testCase.Key, "Subscription Scenario:")
+}
+
+// isHeimdallTestCase reports whether the test case queries Heimdall besides the node.
+func isHeimdallTestCase(testCase TestCase) bool {
+	for _, tag := range testCase.Tags {
+		if tag == heimdallTag {
+			return true
+		}
+	}
+	return false
+}
+
+// isFilterChangesTestCase reports whether the test case polls a filter, which only
+// works reliably when all requests hit the same node.
+func isFilterChangesTestCase(testCase TestCase) bool {
+	return strings.Contains(testCase.Key, "eth_getFilterChanges")
+}
+
+// transportError wraps a failure to reach the node or to read its answer, as
+// opposed to an answer that could not be understood.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// CallEthereumRPC performs an RPC call to an Ethereum node.
+func CallEthereumRPC(httpClient *http.Client, reqPayload []Request, rpcURL string) ([]Response, error) {
+	// Serialize the request to JSON
+	reqBytes, err := json.Marshal(reqPayload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request: %w", err)
+	}
+
+	body, err := postJSON(httpClient, reqBytes, rpcURL)
 	if err != nil {
 		return nil, err
 	}
 
-	// Return the final result
+	// Deserialize the response
+	var rpcResp []Response
+	if err := json.Unmarshal(body, &rpcResp); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response: %w", err)
+	}
+
 	return rpcResp, nil
 }
 
+// postJSON posts a JSON-RPC request body and returns the body of the answer.
+func postJSON(httpClient *http.Client, reqBytes []byte, rpcURL string) ([]byte, error) {
+	// Make the HTTP POST request
+	resp, err := httpClient.Post(rpcURL, "application/json", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &transportError{fmt.Errorf("error making RPC call: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	// Read the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &transportError{fmt.Errorf("error reading response body: %w", err)}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
+
 // NewRequest creates a new Request with Jsonrpc set to "2.0" and other fields given as parameters.
 func NewRequest(method string, params interface{}) *Request {
 	return &Request{
 		JsonRPC: "2.0",
 		Method:  method,
 		Params:  params,
-		ID:      rand.Int(),
+		ID:      randomness.nextRequestID(),
 	}
 }
 
@@ -405,13 +1077,74 @@ func parseResponse[T any](raw json.RawMessage) (*T, error) {
 	return &response, nil
 }
 
+// parseObject decodes a JSON object like parseResponse but, when a field cannot be
+// decoded, reports which one. A null result decodes to a nil pointer.
+func parseObject[T any](raw json.RawMessage) (*T, error) {
+	var object *T
+	err := json.Unmarshal(raw, &object)
+	if err == nil {
+		return object, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) == nil {
+		if fieldErr := findInvalidField(reflect.TypeOf(object).Elem(), fields); fieldErr != nil {
+			return nil, fieldErr
+		}
+	}
+	return nil, fmt.Errorf("error unmarshalling JSON: %w", err)
+}
+
+// findInvalidField decodes every field of a struct type on its own and returns an error
+// naming the first one that fails.
+func findInvalidField(structType reflect.Type, fields map[string]json.RawMessage) error {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Anonymous {
+			if err := findInvalidField(field.Type, fields); err != nil {
+				return err
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, reflect.New(field.Type).Interface()); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// parseReceipts decodes a list of receipts, as returned by eth_getBlockReceipts.
+func parseReceipts(raw json.RawMessage) ([]RPCReceipt, error) {
+	rawReceipts, err := parseResponse[[]json.RawMessage](raw)
+	if err != nil {
+		return nil, err
+	}
+	receipts := make([]RPCReceipt, len(*rawReceipts))
+	for i, rawReceipt := range *rawReceipts {
+		receipt, err := parseObject[RPCReceipt](rawReceipt)
+		if err != nil {
+			return nil, fmt.Errorf("receipt %d: %w", i, err)
+		}
+		if receipt == nil {
+			return nil, fmt.Errorf("receipt %d is null", i)
+		}
+		receipts[i] = *receipt
+	}
+	return receipts, nil
+}
+
 func generateAccountsUsingMnemonic(MNEMONIC string, N int) (accounts Accounts) {
 	wallet, err := hdwallet.NewFromMnemonic(MNEMONIC)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	for i := 0; i <= N; i++ {
+	for i := 0; i < N; i++ {
 		var derivPath = "m/44'/60'/0'/0/" + strconv.Itoa(i)
 		path := hdwallet.MustParseDerivationPath(derivPath)
 		account, err := wallet.Derive(path, false)
@@ -448,9 +1181,8 @@ func generateAccountUsingPrivKey(hexKey string) (*Account, error) {
 	address := crypto.PubkeyToAddress(*publicKeyECDSA)
 
 	return &Account{
-		key:   privateKey,
-		addr:  address,
-		nonce: big.NewInt(0),
+		key:  privateKey,
+		addr: address,
 	}, nil
 }
 
@@ -468,174 +1200,94 @@ func hexStringToBigInt(hexString string) (*big.Int, error) {
 	return intValue, nil
 }
 
-func validateBlock(block map[string]interface{}) error {
-	// Check if the block is nil
+// validateBlock performs various checks on a block returned by eth_getBlockBy*
+func validateBlock(block *RPCBlock) error {
 	if block == nil {
 		return fmt.Errorf("block not found")
 	}
 
-	// Validate specific required fields
-	requiredFields := []string{
-		"baseFeePerGas", "difficulty", "gasLimit", "gasUsed",
-		"hash", "number", "timestamp", "parentHash", "miner",
-	}
-
-	for _, field := range requiredFields {
-		if _, ok := block[field]; !ok {
-			return fmt.Errorf("missing required field: %s", field)
-		}
-	}
-
-	// Validate "gasLimit" (cannot be zero)
-	if err := validateHexBigInt(block["gasLimit"], "gasLimit", func(value *big.Int) error {
-		if value.Cmp(big.NewInt(0)) == 0 {
-			return errors.New("gasLimit cannot be zero")
-		}
-		return nil
-	}); err != nil {
+	if err := checkRequiredFields("block",
+		responseField{"baseFeePerGas", block.BaseFee != nil},
+		responseField{"difficulty", block.Difficulty != nil},
+		responseField{"gasLimit", block.GasLimit != nil},
+		responseField{"gasUsed", block.GasUsed != nil},
+		responseField{"hash", block.Hash != nil},
+		responseField{"number", block.Number != nil},
+		responseField{"timestamp", block.Timestamp != nil},
+		responseField{"parentHash", block.ParentHash != nil},
+		responseField{"miner", block.Miner != nil},
+		responseField{"stateRoot", block.StateRoot != nil},
+		responseField{"transactions", block.Transactions != nil},
+	); err != nil {
 		return err
 	}
 
-	// Validate "timestamp" (should be within 1 hour of the current time)
-	if err := validateHexBigInt(block["timestamp"], "timestamp", func(value *big.Int) error {
-		currentTime := time.Now().Unix()
-		blockTime := value.Int64()
-		if blockTime < currentTime-3600 || blockTime > currentTime+3600 {
-			return fmt.Errorf("timestamp is too far from current time: %d", blockTime)
-		}
-		return nil
-	}); err != nil {
-		return err
+	if *block.GasLimit == 0 {
+		return errors.New("invalid gasLimit: cannot be zero")
 	}
-
-	// Validate "miner" address length (must be 42 characters for Ethereum addresses)
-	if miner, ok := block["miner"].(string); !ok || len(miner) != 42 {
-		return fmt.Errorf("invalid miner address length: %s", miner)
+	if *block.GasUsed > *block.GasLimit {
+		return fmt.Errorf("invalid gasUsed: %d exceeds gasLimit %d", *block.GasUsed, *block.GasLimit)
 	}
 
-	// Validate "hash" (must be 66 characters )
-	if hash, ok := block["hash"].(string); !ok || len(hash) != 66 {
-		return fmt.Errorf("invalid hash: %s", hash)
+	// Timestamp should be within 1 hour of the current time
+	currentTime := clock().Unix()
+	blockTime := int64(*block.Timestamp)
+	if blockTime < currentTime-3600 || blockTime > currentTime+3600 {
+		return fmt.Errorf("invalid timestamp: too far from current time: %d", blockTime)
 	}
 
-	// Additional checks for "parentHash" length
-	if parentHash, ok := block["parentHash"].(string); !ok || len(parentHash) != 66 {
-		return fmt.Errorf("invalid parentHash length: %s", parentHash)
+	if (*block.Hash == common.Hash{}) {
+		return errors.New("invalid hash: cannot be zero")
 	}
 
-	// If all validations pass, return nil
-	return nil
+	return verifyHeaderHash(&block.RPCHeader)
 }
 
 // validateHeader performs various checks on an Ethereum block header
-func validateHeader(header map[string]interface{}) error {
-	// Helper function to validate hex string
-	validateHex := func(value string, fieldName string, exactLength int) error {
-		if !strings.HasPrefix(value, "0x") {
-			return fmt.Errorf("%s must start with 0x", fieldName)
-		}
-
-		value = strings.TrimPrefix(value, "0x")
-		if exactLength > 0 && len(value) != exactLength {
-			return fmt.Errorf("%s must be exactly %d hex chars long", fieldName, exactLength)
-		}
-
-		// Handle empty hex string after 0x
-		if len(value) == 0 {
-			return fmt.Errorf("%s cannot be empty", fieldName)
-		}
-
-		// Pad single digits with leading zero for hex decoding
-		if len(value)%2 != 0 {
-			value = "0" + value
-		}
-
-		if _, err := hex.DecodeString(value); err != nil {
-			return fmt.Errorf("%s contains invalid hex characters", fieldName)
-		}
-
-		return nil
-	}
-
-	// Required fields check
-	requiredFields := []string{
-		"baseFeePerGas", "difficulty", "extraData", "gasLimit",
-		"gasUsed", "hash", "logsBloom", "miner", "mixHash",
-		"nonce", "number", "parentHash", "receiptsRoot",
-		"sha3Uncles", "stateRoot", "timestamp", "transactionsRoot",
-	}
-
-	for _, field := range requiredFields {
-		if _, exists := header[field]; !exists {
-			return fmt.Errorf("missing required field: %s", field)
-		}
-	}
-
-	// Validate hash (66 chars including 0x prefix = 32 bytes)
-	if err := validateHex(header["hash"].(string), "hash", 64); err != nil {
+func validateHeader(header *RPCHeader) error {
+	if header == nil {
+		return fmt.Errorf("header not found")
+	}
+
+	// Hashes, addresses, bloom and nonce are checked for their exact length while decoding
+	if err := checkRequiredFields("header",
+		responseField{"baseFeePerGas", header.BaseFee != nil},
+		responseField{"difficulty", header.Difficulty != nil},
+		responseField{"extraData", header.ExtraData != nil},
+		responseField{"gasLimit", header.GasLimit != nil},
+		responseField{"gasUsed", header.GasUsed != nil},
+		responseField{"hash", header.Hash != nil},
+		responseField{"logsBloom", header.LogsBloom != nil},
+		responseField{"miner", header.Miner != nil},
+		responseField{"mixHash", header.MixHash != nil},
+		responseField{"nonce", header.Nonce != nil},
+		responseField{"number", header.Number != nil},
+		responseField{"parentHash", header.ParentHash != nil},
+		responseField{"receiptsRoot", header.ReceiptsRoot != nil},
+		responseField{"sha3Uncles", header.UncleHash != nil},
+		responseField{"stateRoot", header.StateRoot != nil},
+		responseField{"timestamp", header.Timestamp != nil},
+		responseField{"transactionsRoot", header.TransactionsRoot != nil},
+	); err != nil {
 		return err
 	}
 
-	// Validate parent hash
-	if err := validateHex(header["parentHash"].(string), "parentHash", 64); err != nil {
-		return err
-	}
-
-	// Validate miner address (42 chars including 0x prefix = 20 bytes)
-	if err := validateHex(header["miner"].(string), "miner", 40); err != nil {
-		return err
-	}
-
-	// Validate timestamps
-	timestampHex := strings.TrimPrefix(header["timestamp"].(string), "0x")
-	timestamp, _ := new(big.Int).SetString(timestampHex, 16)
-	blockTime := time.Unix(timestamp.Int64(), 0)
-
 	// Check if timestamp is within reasonable bounds (not more than 1 hour in the future)
-	if blockTime.After(time.Now().Add(time.Hour)) {
-		return fmt.Errorf("block timestamp is too far in the future")
-	}
-
-	// Validate gas limits
-	gasLimitHex := strings.TrimPrefix(header["gasLimit"].(string), "0x")
-	gasLimit, _ := new(big.Int).SetString(gasLimitHex, 16)
-
-	gasUsedHex := strings.TrimPrefix(header["gasUsed"].(string), "0x")
-	gasUsed, _ := new(big.Int).SetString(gasUsedHex, 16)
-
-	// Check if gasUsed exceeds gasLimit
-	if gasUsed.Cmp(gasLimit) > 0 {
-		return fmt.Errorf("gas used exceeds gas limit")
-	}
-
-	// Validate difficulty (no exact length requirement)
-	if err := validateHex(header["difficulty"].(string), "difficulty", 0); err != nil {
-		return err
-	}
-
-	// Validate nonce
-	if err := validateHex(header["nonce"].(string), "nonce", 16); err != nil {
-		return err
-	}
-
-	// Validate logs bloom (256 bytes = 512 hex chars)
-	if err := validateHex(header["logsBloom"].(string), "logsBloom", 512); err != nil {
-		return err
+	blockTime := time.Unix(int64(*header.Timestamp), 0)
+	if blockTime.After(clock().Add(time.Hour)) {
+		return fmt.Errorf("invalid timestamp: block timestamp is too far in the future")
 	}
 
-	// Validate block number
-	if err := validateHex(header["number"].(string), "number", 0); err != nil {
-		return err
+	if *header.GasUsed > *header.GasLimit {
+		return fmt.Errorf("invalid gasUsed: %d exceeds gasLimit %d", *header.GasUsed, *header.GasLimit)
 	}
 
-	// Extra data length validation
-	extraData := strings.TrimPrefix(header["extraData"].(string), "0x")
-	if len(extraData) == 0 {
-		return fmt.Errorf("extra data cannot be empty")
+	// Bor keeps the vanity and the block signature in the extra data
+	if len(*header.ExtraData) == 0 {
+		return fmt.Errorf("invalid extraData: cannot be empty")
 	}
 
-	// All validations passed
-	return nil
+	return verifyHeaderHash(header)
 }
 
 // validateSnapshot performs validation checks on a Snapshot struct
@@ -767,140 +1419,187 @@ func validateBlockSigners(bs *bor.BlockSigners) error {
 	return nil
 }
 
-func validateStateSyncTxReceipt(receipt map[string]interface{}) error {
-	// Validate from and to addresses are 0x0
-	zeroAddress := "0x0000000000000000000000000000000000000000"
-
-	from, ok := receipt["from"].(string)
-	if !ok || !strings.EqualFold(from, zeroAddress) {
-		return fmt.Errorf("invalid 'from' address: expected %s, got %s", zeroAddress, from)
+// stateSyncLogTopic is the StateCommitted event emitted by the state receiver contract.
+var stateSyncLogTopic = common.HexToHash("0x5a22725590b0a51c923940223f7458512164b1113359a735e86e7f27f44791ee")
+
+func validateStateSyncTxReceipt(receipt *RPCReceipt) error {
+	if receipt == nil {
+		return fmt.Errorf("receipt not found")
+	}
+	if err := checkRequiredFields("receipt",
+		responseField{"from", receipt.From != nil},
+		responseField{"to", receipt.To != nil},
+		responseField{"transactionIndex", receipt.TransactionIndex != nil},
+		responseField{"cumulativeGasUsed", receipt.CumulativeGasUsed != nil},
+		responseField{"effectiveGasPrice", receipt.EffectiveGasPrice != nil},
+		responseField{"gasUsed", receipt.GasUsed != nil},
+		responseField{"logs", receipt.Logs != nil},
+	); err != nil {
+		return err
 	}
 
-	to, ok := receipt["to"].(string)
-	if !ok || !strings.EqualFold(to, zeroAddress) {
-		return fmt.Errorf("invalid 'to' address: expected %s, got %s", zeroAddress, to)
+	// Validate from and to addresses are 0x0
+	zeroAddress := common.Address{}
+	if *receipt.From != zeroAddress {
+		return fmt.Errorf("invalid 'from' address: expected %s, got %s", zeroAddress, receipt.From)
+	}
+	if *receipt.To != zeroAddress {
+		return fmt.Errorf("invalid 'to' address: expected %s, got %s", zeroAddress, receipt.To)
 	}
 
 	// Validate gas values are 0
-	if receipt["cumulativeGasUsed"] != "0x0" {
+	if *receipt.CumulativeGasUsed != 0 {
 		return fmt.Errorf("cumulativeGasUsed must be 0x0")
 	}
-
-	if receipt["effectiveGasPrice"] != "0x0" {
+	if receipt.EffectiveGasPrice.ToInt().Sign() != 0 {
 		return fmt.Errorf("effectiveGasPrice must be 0x0")
 	}
-
-	if receipt["gasUsed"] != "0x0" {
+	if *receipt.GasUsed != 0 {
 		return fmt.Errorf("gasUsed must be 0x0")
 	}
 
 	// Validate logs contain required state sync log
-	logs, ok := receipt["logs"].([]interface{})
-	if !ok {
-		return fmt.Errorf("invalid logs field")
+	for _, logEvent := range receipt.Logs {
+		if logEvent.Address == stateReceiverAddress && len(logEvent.Topics) > 0 && logEvent.Topics[0] == stateSyncLogTopic {
+			return nil
+		}
+	}
+	return fmt.Errorf("state sync log not found")
+}
+
+func handleGetStateSyncBlockReceipts(rm *ResponseMap, method string, txReceipts []RPCReceipt) error {
+	if len(txReceipts) == 0 {
+		return fmt.Errorf("no receipts returned by %s for block %s", method, rm.stateSyncBlockHash)
 	}
 
-	stateSyncAddress := "0x0000000000000000000000000000000000001001"
-	expectedTopic, err := hex.DecodeString("5a22725590b0a51c923940223f7458512164b1113359a735e86e7f27f44791ee")
+	// state sync tx must always be the last one
+	stateSyncTx := &txReceipts[len(txReceipts)-1]
+	err := validateStateSyncTxReceipt(stateSyncTx)
 	if err != nil {
-		return fmt.Errorf("failed to decode expected topic: %v", err)
+		return fmt.Errorf("last receipt on block must be state sync tx: %s", err)
+	}
+	if int(*stateSyncTx.TransactionIndex) != rm.stateSyncTxIndex {
+		return fmt.Errorf("error on state sync tx index in block %s: transaction index on method %s: %d | transaction index on method eth_getTransactionReceipt: %d", rm.stateSyncBlockHash, method, *stateSyncTx.TransactionIndex, rm.stateSyncTxIndex)
 	}
 
-	foundStateSyncLog := false
-	for _, logInterface := range logs {
-		logEvent, ok := logInterface.(map[string]interface{})
-		if !ok {
-			continue
-		}
+	return nil
+}
 
-		address, ok := logEvent["address"].(string)
-		if !ok || !strings.EqualFold(address, stateSyncAddress) {
-			continue
-		}
+// ValidateCodeHash checks if the provided runtime code matches the account's codeHash
+func ValidateCodeHash(runtimeCode []byte, accountResult *accountResult) error {
+	calculatedCodeHash := crypto.Keccak256Hash(runtimeCode)
 
-		topics, ok := logEvent["topics"].([]interface{})
-		if !ok || len(topics) == 0 {
-			continue
-		}
+	if calculatedCodeHash != accountResult.CodeHash {
+		return fmt.Errorf("code hash mismatch: calculated %s, account has %s",
+			calculatedCodeHash.Hex(), accountResult.CodeHash.Hex())
+	}
+	return nil
+}
 
-		firstTopic, ok := topics[0].(string)
-		if !ok {
-			continue
-		}
+// validateTypedTransaction checks that a transaction returned by the node matches the
+// signed typed transaction that was sent.
+func validateTypedTransaction(tx *RPCTransaction, expected *types.Transaction) error {
+	if tx.Hash != expected.Hash() {
+		return fmt.Errorf("invalid hash: expected %s, actual %s", expected.Hash(), tx.Hash)
+	}
+	if uint8(tx.Type) != expected.Type() {
+		return fmt.Errorf("invalid type: expected %d, actual %d", expected.Type(), tx.Type)
+	}
+	if uint64(tx.Nonce) != expected.Nonce() {
+		return fmt.Errorf("invalid nonce: expected %d, actual %d", expected.Nonce(), tx.Nonce)
+	}
+	if tx.ChainID == nil || tx.ChainID.ToInt().Cmp(expected.ChainId()) != 0 {
+		return fmt.Errorf("invalid chainId: expected %s, actual %v", expected.ChainId(), tx.ChainID)
+	}
 
-		// Remove "0x" prefix if present
-		firstTopic = strings.TrimPrefix(firstTopic, "0x")
-		topicBytes, err := hex.DecodeString(firstTopic)
-		if err != nil {
-			continue
+	if expected.Type() == types.DynamicFeeTxType {
+		if tx.GasFeeCap == nil || tx.GasFeeCap.ToInt().Cmp(expected.GasFeeCap()) != 0 {
+			return fmt.Errorf("invalid maxFeePerGas: expected %s, actual %v", expected.GasFeeCap(), tx.GasFeeCap)
 		}
-
-		if bytes.Equal(topicBytes, expectedTopic) {
-			foundStateSyncLog = true
-			break
+		if tx.GasTipCap == nil || tx.GasTipCap.ToInt().Cmp(expected.GasTipCap()) != 0 {
+			return fmt.Errorf("invalid maxPriorityFeePerGas: expected %s, actual %v", expected.GasTipCap(), tx.GasTipCap)
 		}
+	} else if tx.GasFeeCap != nil || tx.GasTipCap != nil {
+		return fmt.Errorf("maxFeePerGas and maxPriorityFeePerGas must not be set on a type %d transaction", expected.Type())
 	}
 
-	if !foundStateSyncLog {
-		return fmt.Errorf("state sync log not found")
+	if tx.Accesses == nil {
+		return fmt.Errorf("accessList must be set on a type %d transaction", expected.Type())
+	}
+	if len(*tx.Accesses) != len(expected.AccessList()) {
+		return fmt.Errorf("invalid accessList length: expected %d, actual %d", len(expected.AccessList()), len(*tx.Accesses))
+	}
+	for i, tuple := range expected.AccessList() {
+		actual := (*tx.Accesses)[i]
+		if actual.Address != tuple.Address || len(actual.StorageKeys) != len(tuple.StorageKeys) {
+			return fmt.Errorf("invalid accessList entry %d: expected %v, actual %v", i, tuple, actual)
+		}
+		for j, key := range tuple.StorageKeys {
+			if actual.StorageKeys[j] != key {
+				return fmt.Errorf("invalid accessList entry %d: expected %v, actual %v", i, tuple, actual)
+			}
+		}
 	}
 
-	return nil
-}
-
-func handleGetStateSyncBlockReceipts(rm *ResponseMap, method string, txReceipts *[]map[string]interface{}) error {
-
-	// state sync tx must always be the last one
-	stateSyncTx := (*txReceipts)[len(*txReceipts)-1]
-
-	err := validateStateSyncTxReceipt((*txReceipts)[len(*txReceipts)-1])
-	if err != nil {
-		return fmt.Errorf("last receipt on block must be state sync tx: %s", err)
+	// typed transactions carry the signature parity in yParity, v must be equal to it
+	v, r, s := expected.RawSignatureValues()
+	if tx.YParity == nil {
+		return fmt.Errorf("yParity must be set on a type %d transaction", expected.Type())
 	}
-	txIndexHexStringWithoutPrefix := (stateSyncTx)["transactionIndex"].(string)[2:]
-	txIndexOnMethod, err := strconv.ParseInt(txIndexHexStringWithoutPrefix, 16, 0)
-	if err != nil {
-		return fmt.Errorf("error converting hex to int:%s", err)
+	if uint64(*tx.YParity) != v.Uint64() || tx.V == nil || tx.V.ToInt().Cmp(v) != 0 {
+		return fmt.Errorf("invalid yParity: expected %s, actual %d (v: %v)", v, *tx.YParity, tx.V)
 	}
-	if int(txIndexOnMethod) != rm.stateSyncTxIndex {
-		return fmt.Errorf("error on state sync tx index in block %s: transaction index on method %s: %d | transaction index on method eth_getTransactionReceipt: %d", rm.stateSyncBlockHash, method, txIndexOnMethod, rm.stateSyncTxIndex)
+	if tx.R == nil || tx.R.ToInt().Cmp(r) != 0 || tx.S == nil || tx.S.ToInt().Cmp(s) != 0 {
+		return fmt.Errorf("invalid signature values: expected r=%s s=%s, actual r=%v s=%v", r, s, tx.R, tx.S)
 	}
-
 	return nil
 }
 
-func validateHexBigInt(value interface{}, fieldName string, customValidation func(*big.Int) error) error {
-	strValue, ok := value.(string)
-	if !ok {
-		return fmt.Errorf("invalid type for %s, expected string", fieldName)
+// validateTypedTransactionReceipt checks the receipt of a mined typed transaction and
+// returns its effective gas price.
+func validateTypedTransactionReceipt(receipt *RPCReceipt, expected *types.Transaction) (*big.Int, error) {
+	if receipt == nil || receipt.BlockNumber == nil {
+		return nil, fmt.Errorf("transaction %s not mined", expected.Hash())
+	}
+	if err := checkRequiredFields("receipt",
+		responseField{"transactionHash", receipt.TransactionHash != nil},
+		responseField{"type", receipt.Type != nil},
+		responseField{"status", receipt.Status != nil},
+		responseField{"gasUsed", receipt.GasUsed != nil},
+		responseField{"effectiveGasPrice", receipt.EffectiveGasPrice != nil},
+	); err != nil {
+		return nil, err
 	}
-
-	// Ensure it starts with "0x"
-	if len(strValue) < 3 || strValue[:2] != "0x" {
-		return fmt.Errorf("%s must be a hex string prefixed with 0x", fieldName)
+	if *receipt.TransactionHash != expected.Hash() {
+		return nil, fmt.Errorf("invalid transactionHash: expected %s, actual %s", expected.Hash(), receipt.TransactionHash)
 	}
-
-	// Convert to big.Int
-	bigValue, success := new(big.Int).SetString(strValue[2:], 16)
-	if !success {
-		return fmt.Errorf("invalid hex string for %s: %s", fieldName, strValue)
+	if uint8(*receipt.Type) != expected.Type() {
+		return nil, fmt.Errorf("invalid type: expected %d, actual %d", expected.Type(), *receipt.Type)
 	}
-
-	// Apply custom validation if provided
-	if customValidation != nil {
-		return customValidation(bigValue)
+	if uint64(*receipt.Status) != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("transaction %s reverted", expected.Hash())
+	}
+	if *receipt.GasUsed == 0 || uint64(*receipt.GasUsed) > expected.Gas() {
+		return nil, fmt.Errorf("invalid gasUsed: %d with gas limit %d", *receipt.GasUsed, expected.Gas())
 	}
 
-	return nil
+	effectiveGasPrice := receipt.EffectiveGasPrice.ToInt()
+	if effectiveGasPrice.Cmp(expected.GasFeeCap()) > 0 {
+		return nil, fmt.Errorf("effectiveGasPrice %s is above the fee cap %s", effectiveGasPrice, expected.GasFeeCap())
+	}
+	if expected.Type() == types.AccessListTxType && effectiveGasPrice.Cmp(expected.GasPrice()) != 0 {
+		return nil, fmt.Errorf("invalid effectiveGasPrice: expected %s, actual %s", expected.GasPrice(), effectiveGasPrice)
+	}
+	return effectiveGasPrice, nil
 }
 
-// ValidateCodeHash checks if the provided runtime code matches the account's codeHash
-func ValidateCodeHash(runtimeCode []byte, accountResult *accountResult) error {
-	calculatedCodeHash := crypto.Keccak256Hash(runtimeCode)
-
-	if calculatedCodeHash != accountResult.CodeHash {
-		return fmt.Errorf("code hash mismatch: calculated %s, account has %s",
-			calculatedCodeHash.Hex(), accountResult.CodeHash.Hex())
+func validateUnsubscribe(resp Response) error {
+	unsubscribed, err := parseResponse[bool](resp.Result)
+	if err != nil {
+		return err
+	}
+	if !*unsubscribed {
+		return fmt.Errorf("subscription was not removed")
 	}
 	return nil
 }
@@ -919,20 +1618,55 @@ func generateInputForCallGetValue(key string) []byte {
 }
 
 func generateRawTransaction(nonce uint64, gasLimit uint64, gasPrice *big.Int, data []byte, privateKey *ecdsa.PrivateKey, chainID *big.Int) string {
-	tx := types.NewTx(&types.LegacyTx{
+	signedTx := signTransaction(&types.LegacyTx{
 		Nonce:    nonce,
 		To:       nil,
 		Value:    big.NewInt(0),
 		Gas:      gasLimit,
 		GasPrice: gasPrice,
 		Data:     data,
-	})
+	}, privateKey, chainID)
+	return encodeRawTransaction(signedTx)
+}
+
+// generateDynamicFeeTransaction creates a signed EIP-1559 (type 2) contract creation transaction.
+func generateDynamicFeeTransaction(nonce uint64, gasLimit uint64, gasTipCap *big.Int, gasFeeCap *big.Int, data []byte, privateKey *ecdsa.PrivateKey, chainID *big.Int) *types.Transaction {
+	return signTransaction(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		To:        nil,
+		Value:     big.NewInt(0),
+		Gas:       gasLimit,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Data:      data,
+	}, privateKey, chainID)
+}
+
+// generateAccessListTransaction creates a signed EIP-2930 (type 1) contract creation transaction.
+func generateAccessListTransaction(nonce uint64, gasLimit uint64, gasPrice *big.Int, accessList types.AccessList, data []byte, privateKey *ecdsa.PrivateKey, chainID *big.Int) *types.Transaction {
+	return signTransaction(&types.AccessListTx{
+		ChainID:    chainID,
+		Nonce:      nonce,
+		To:         nil,
+		Value:      big.NewInt(0),
+		Gas:        gasLimit,
+		GasPrice:   gasPrice,
+		AccessList: accessList,
+		Data:       data,
+	}, privateKey, chainID)
+}
+
+func signTransaction(txData types.TxData, privateKey *ecdsa.PrivateKey, chainID *big.Int) *types.Transaction {
 	signer := types.LatestSignerForChainID(chainID)
-	signedTx, err := types.SignTx(tx, signer, privateKey)
+	signedTx, err := types.SignTx(types.NewTx(txData), signer, privateKey)
 	if err != nil {
 		log.Fatalf("Failed to sign transaction: %v", err)
 	}
+	return signedTx
+}
 
+func encodeRawTransaction(signedTx *types.Transaction) string {
 	rawTxBytes, err := signedTx.MarshalBinary()
 	if err != nil {
 		log.Fatalf("Failed to marshal transaction: %v", err)
@@ -956,7 +1690,8 @@ func prepareEstimateGasRequest(from Account, input []byte) map[string]interface{
 
 var testCases = []TestCase{
 	{
-		Key: "eth_chainId",
+		Key:      "eth_chainId",
+		Produces: []string{"chainId"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_chainId", []interface{}{}), nil
 		},
@@ -975,7 +1710,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "eth_blockNumber",
+		Key:      "eth_blockNumber",
+		Produces: []string{"mostRecentBlockNumber"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_blockNumber", []interface{}{}), nil
 		},
@@ -993,27 +1729,25 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "eth_getTransactionCount",
+		Key:      "eth_getTransactionCount",
+		Produces: []string{"accountNonce"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getTransactionCount", []interface{}{rm.account.addr, "latest"}), nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
-			parsed, err := parseResponse[string](resp.Result)
-			if err != nil {
-				return err
-			}
-			intValue, err := hexStringToBigInt(*parsed)
+			count, err := parseResponse[hexutil.Uint64](resp.Result)
 			if err != nil {
 				return err
 			}
 
 			// set nonce for accounts[0]
-			rm.account.nonce = intValue
+			rm.nonces.sync(rm.account.addr, uint64(*count))
 			return nil
 		},
 	},
 	{
-		Key: "bor_getCurrentProposer",
+		Key:      "bor_getCurrentProposer",
+		Produces: []string{"currentProposerAddress"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("bor_getCurrentProposer", []interface{}{}), nil
 		},
@@ -1057,38 +1791,68 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "eth_getBlockByNumber",
+		Key:      "eth_getBlockByNumber",
+		Requires: []string{"mostRecentBlockNumber"},
+		Produces: []string{"mostRecentBlock", "mostRecentBlockHash", "mostRecentBlockParentHash", "mostRecentBlockStateRoot", "mostRecentBlockSigner"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getBlockByNumber", []interface{}{fmt.Sprintf("0x%x", rm.mostRecentBlockNumber), true}), nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
-			block, err := parseResponse[map[string]interface{}](resp.Result)
+			block, err := parseObject[RPCBlock](resp.Result)
+			if err != nil {
+				return err
+			}
+			err = validateBlock(block)
 			if err != nil {
 				return err
 			}
-			err = validateBlock(*block)
+			signer, err := borSealSigner(&block.RPCHeader)
 			if err != nil {
 				return err
 			}
-			blockHash, _ := (*block)["hash"].(string)
-			parentHash, _ := (*block)["parentHash"].(string)
-			rm.mostRecentBlockHash = common.HexToHash(blockHash)
-			rm.mostRecentBlockParentHash = common.HexToHash(parentHash)
+			rm.mostRecentBlock = block
+			rm.mostRecentBlockHash = *block.Hash
+			rm.mostRecentBlockParentHash = *block.ParentHash
+			rm.mostRecentBlockStateRoot = *block.StateRoot
+			rm.mostRecentBlockSigner = signer
 			return nil
 		},
 	},
 	{
-		Key: "eth_getBlockByHash",
+		Key:      "eth_getBlockReceipts",
+		Requires: []string{"mostRecentBlock", "mostRecentBlockHash"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getBlockReceipts", []interface{}{rm.mostRecentBlockHash}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			receipts, err := parseReceipts(resp.Result)
+			if err != nil {
+				return err
+			}
+			return verifyBlockRoots(rm.mostRecentBlock, receipts)
+		},
+	},
+	{
+		Key:      "eth_getBlockByHash",
+		Requires: []string{"mostRecentBlockParentHash"},
+		Produces: []string{"mostRecentParentBlockSigner"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			// requests most recent parent block
 			return NewRequest("eth_getBlockByHash", []interface{}{fmt.Sprintf("0x%x", rm.mostRecentBlockParentHash), true}), nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
-			block, err := parseResponse[map[string]interface{}](resp.Result)
+			block, err := parseObject[RPCBlock](resp.Result)
 			if err != nil {
 				return err
 			}
-			err = validateBlock(*block)
+			err = validateBlock(block)
+			if err != nil {
+				return err
+			}
+			if *block.Hash != rm.mostRecentBlockParentHash {
+				return fmt.Errorf("invalid block hash: requested %s, received %s", rm.mostRecentBlockParentHash, block.Hash)
+			}
+			rm.mostRecentParentBlockSigner, err = borSealSigner(&block.RPCHeader)
 			if err != nil {
 				return err
 			}
@@ -1096,16 +1860,49 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "eth_getHeaderByNumber",
+		Key:      "eth_getProof (non-existent account)",
+		Requires: []string{"mostRecentBlockNumber", "mostRecentBlockStateRoot"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			// the address of a contract the account would deploy with the last nonce cannot exist
+			absent := crypto.CreateAddress(rm.account.addr, math.MaxUint64)
+			return NewRequest("eth_getProof", []interface{}{absent, []common.Hash{{}}, fmt.Sprintf("0x%x", rm.mostRecentBlockNumber)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			accountResult, err := parseResponse[accountResult](resp.Result)
+			if err != nil {
+				return err
+			}
+			if len(accountResult.AccountProof) == 0 {
+				return fmt.Errorf("must not be an empty account proof array")
+			}
+			// the proof must show that the account is not part of the state
+			if err := verifyAccountProof(rm.mostRecentBlockStateRoot, accountResult); err != nil {
+				return err
+			}
+			if accountResult.StorageHash != types.EmptyRootHash || accountResult.CodeHash != types.EmptyCodeHash {
+				return fmt.Errorf("non-existent account must have empty storageHash and codeHash, actual %s and %s", accountResult.StorageHash, accountResult.CodeHash)
+			}
+			if len(accountResult.StorageProof) != 1 {
+				return fmt.Errorf("invalid storage proof array: expected 1 entry, actual %d", len(accountResult.StorageProof))
+			}
+			if _, err := verifyStorageProof(accountResult.StorageHash, accountResult.StorageProof[0]); err != nil {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "eth_getHeaderByNumber",
+		Requires: []string{"mostRecentBlockNumber"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getHeaderByNumber", []interface{}{fmt.Sprintf("0x%x", rm.mostRecentBlockNumber)}), nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
-			header, err := parseResponse[map[string]interface{}](resp.Result)
+			header, err := parseObject[RPCHeader](resp.Result)
 			if err != nil {
 				return err
 			}
-			err = validateHeader(*header)
+			err = validateHeader(header)
 			if err != nil {
 				return err
 			}
@@ -1113,16 +1910,17 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "eth_getHeaderByHash",
+		Key:      "eth_getHeaderByHash",
+		Requires: []string{"mostRecentBlockHash"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getHeaderByHash", []interface{}{fmt.Sprintf("0x%x", rm.mostRecentBlockHash)}), nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
-			header, err := parseResponse[map[string]interface{}](resp.Result)
+			header, err := parseObject[RPCHeader](resp.Result)
 			if err != nil {
 				return err
 			}
-			err = validateHeader(*header)
+			err = validateHeader(header)
 			if err != nil {
 				return err
 			}
@@ -1151,7 +1949,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "eth_gasPrice",
+		Key:      "eth_gasPrice",
+		Produces: []string{"gasPrice"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_gasPrice", []interface{}{}), nil
 		},
@@ -1173,7 +1972,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "eth_maxPriorityFeePerGas",
+		Key:      "eth_maxPriorityFeePerGas",
+		Produces: []string{"maxPriorityFeePerGas"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_maxPriorityFeePerGas", []interface{}{}), nil
 		},
@@ -1190,11 +1990,13 @@ var testCases = []TestCase{
 			if (*priorityFeePerGas).Cmp(big.NewInt(0)) < 0 {
 				return fmt.Errorf("gas price must be equal or greater than 0")
 			}
+			rm.maxPriorityFeePerGas = priorityFeePerGas
 			return nil
 		},
 	},
 	{
-		Key: "eth_feeHistory",
+		Key:      "eth_feeHistory",
+		Produces: []string{"nextBaseFee"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_feeHistory", []interface{}{4, "latest", []int{25, 75}}), nil
 		},
@@ -1241,6 +2043,9 @@ var testCases = []TestCase{
 					}
 				}
 			}
+
+			// the last base fee is the one of the block after the newest one returned
+			rm.nextBaseFee = (*big.Int)(feeHistory.BaseFee[len(feeHistory.BaseFee)-1])
 			return nil
 		},
 	},
@@ -1261,7 +2066,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "bor_getAuthor (by number)",
+		Key:      "bor_getAuthor (by number)",
+		Requires: []string{"mostRecentBlockNumber", "mostRecentBlockSigner"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("bor_getAuthor", []interface{}{fmt.Sprintf("0x%x", rm.mostRecentBlockNumber)}), nil
 		},
@@ -1273,6 +2079,9 @@ var testCases = []TestCase{
 			if (*address == common.Address{}) {
 				return fmt.Errorf("invalid author address")
 			}
+			if *address != rm.mostRecentBlockSigner {
+				return fmt.Errorf("invalid author address: block %s is sealed by %s, got %s", rm.mostRecentBlockNumber, rm.mostRecentBlockSigner, address)
+			}
 			return nil
 		},
 	},
@@ -1293,7 +2102,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "bor_getAuthor (by hash)",
+		Key:      "bor_getAuthor (by hash)",
+		Requires: []string{"mostRecentBlockParentHash", "mostRecentParentBlockSigner"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("bor_getAuthor", []interface{}{rm.mostRecentBlockParentHash}), nil
 		},
@@ -1305,11 +2115,15 @@ var testCases = []TestCase{
 			if (*address == common.Address{}) {
 				return fmt.Errorf("invalid author address")
 			}
+			if *address != rm.mostRecentParentBlockSigner {
+				return fmt.Errorf("invalid author address: block %s is sealed by %s, got %s", rm.mostRecentBlockParentHash, rm.mostRecentParentBlockSigner, address)
+			}
 			return nil
 		},
 	},
 	{
-		Key: "bor_getRootHash",
+		Key:      "bor_getRootHash",
+		Requires: []string{"mostRecentBlockNumber"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("bor_getRootHash", []interface{}{new(big.Int).Sub(rm.mostRecentBlockNumber, big.NewInt(20)), rm.mostRecentBlockNumber}), nil
 		},
@@ -1321,7 +2135,31 @@ var testCases = []TestCase{
 			if len(*rootHash) != 64 {
 				return fmt.Errorf("invalid root hash size")
 			}
-			return nil
+			end := rm.mostRecentBlockNumber.Uint64()
+			return verifyRootHash(rm.client, end-20, end, *rootHash)
+		},
+	},
+	{
+		Key:  "bor_getRootHash (latest Heimdall checkpoint)",
+		Tags: []string{heimdallTag},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			checkpoint, err := rm.heimdall.latestCheckpoint()
+			if err != nil {
+				return nil, err
+			}
+			rm.heimdallCheckpoint = checkpoint
+			return NewRequest("bor_getRootHash", []interface{}{uint64(checkpoint.StartBlock), uint64(checkpoint.EndBlock)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			rootHash, err := parseResponse[string](resp.Result)
+			if err != nil {
+				return err
+			}
+			checkpoint := rm.heimdallCheckpoint
+			if *rootHash != hex.EncodeToString(checkpoint.RootHash) {
+				return fmt.Errorf("invalid root hash of checkpoint %d (blocks %d-%d): Heimdall has %x, got %s", checkpoint.ID, checkpoint.StartBlock, checkpoint.EndBlock, []byte(checkpoint.RootHash), *rootHash)
+			}
+			return verifyRootHash(rm.client, uint64(checkpoint.StartBlock), uint64(checkpoint.EndBlock), *rootHash)
 		},
 	},
 
@@ -1354,7 +2192,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "bor_getSigners",
+		Key:      "bor_getSigners",
+		Requires: []string{"mostRecentBlockNumber"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("bor_getSigners", []interface{}{fmt.Sprintf("0x%x", rm.mostRecentBlockNumber)}), nil
 		},
@@ -1370,7 +2209,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "bor_getSignersAtHash",
+		Key:      "bor_getSignersAtHash",
+		Requires: []string{"mostRecentBlockParentHash", "mostRecentBlockSigner"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("bor_getSignersAtHash", []interface{}{rm.mostRecentBlockParentHash}), nil
 		},
@@ -1382,11 +2222,19 @@ var testCases = []TestCase{
 			if len(*signers) == 0 {
 				return fmt.Errorf("each block must have at least one signer")
 			}
-			return nil
+			// The snapshot at the parent holds the validators allowed to seal the block,
+			// the one at the block itself may already hold the next span's
+			for _, signer := range *signers {
+				if signer == rm.mostRecentBlockSigner {
+					return nil
+				}
+			}
+			return fmt.Errorf("block %s is sealed by %s, which is not a signer at its parent", rm.mostRecentBlockNumber, rm.mostRecentBlockSigner)
 		},
 	},
 	{
-		Key: "bor_getSnapshot",
+		Key:      "bor_getSnapshot",
+		Requires: []string{"mostRecentBlockNumber"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("bor_getSnapshot", []interface{}{fmt.Sprintf("0x%x", rm.mostRecentBlockNumber)}), nil
 		},
@@ -1403,7 +2251,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "bor_getSnapshotAtHash",
+		Key:      "bor_getSnapshotAtHash",
+		Requires: []string{"mostRecentBlockParentHash"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("bor_getSnapshotAtHash", []interface{}{rm.mostRecentBlockParentHash}), nil
 		},
@@ -1453,8 +2302,11 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_fillTransaction",
+		Key:      "Create Transaction Scenario: eth_fillTransaction",
+		Requires: []string{"chainId", "accountNonce"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			// transactions sent later in the same batch reserve nonces, so remember the current one
+			rm.fillTransactionNonce = rm.nonces.peek(rm.account.addr)
 			txParams := prepareEstimateGasRequest(rm.account, generateInputForDeployTestContract(rm.expectedKeyToStoreInContract, rm.expectedValueToStoreInContract))
 			return NewRequest("eth_fillTransaction", []interface{}{txParams}), nil
 		},
@@ -1466,35 +2318,43 @@ var testCases = []TestCase{
 			if transactionResult.Tx.ChainId().Cmp(rm.chainId) != 0 {
 				return fmt.Errorf("invalid chainid: expect %d received %d", rm.chainId, transactionResult.Tx.ChainId())
 			}
-			if transactionResult.Tx.Nonce() != rm.account.nonce.Uint64() {
-				return fmt.Errorf("invalid nonce: expect %d received %d", rm.account.nonce.Uint64(), transactionResult.Tx.Nonce())
+			if transactionResult.Tx.Nonce() != rm.fillTransactionNonce {
+				return fmt.Errorf("invalid nonce: expect %d received %d", rm.fillTransactionNonce, transactionResult.Tx.Nonce())
 			}
 
 			return nil
 		},
 	},
 	{
-		Key: "StateSyncTx Scenario: eth_getLogs",
+		Key:      "StateSyncTx Scenario: eth_getLogs",
+		Requires: []string{"mostRecentBlockNumber"},
+		Produces: []string{"stateSyncTxHash", "stateSyncBlockHash", "stateSyncBlockNumber"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
-			// Define the event topic for StateCommitted(uint256 indexed stateId, bool success)
-			eventSignature := "StateCommitted(uint256,bool)"
-			eventTopic := crypto.Keccak256Hash([]byte(eventSignature))
-
-			address := common.HexToAddress("0x0000000000000000000000000000000000001001")
-
-			fromBlock := new(big.Int).Sub(rm.mostRecentBlockNumber, big.NewInt(30000))
-			if fromBlock.Cmp(big.NewInt(0)) == -1 {
-				fromBlock = big.NewInt(0)
+			return NewRequest("eth_getLogs", []interface{}{stateCommittedFilter(rm.mostRecentBlockNumber)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			logs, err := parseResponse[[]types.Log](resp.Result)
+			if err != nil {
+				return err
 			}
-			// Create the filter object
-			filter := map[string]interface{}{
-				"address":   address.Hex(),
-				"topics":    [][]common.Hash{{eventTopic}},
-				"fromBlock": fmt.Sprintf("0x%x", fromBlock),
-				"toBlock":   fmt.Sprintf("0x%x", rm.mostRecentBlockNumber),
+			if len(*logs) == 0 {
+				return fmt.Errorf("must have at least one state sync event in this block range")
 			}
+			rm.stateSyncTxHash = (*logs)[len(*logs)-1].TxHash
+			rm.stateSyncBlockHash = (*logs)[len(*logs)-1].BlockHash
+
+			blockNumber := (int64)((*logs)[len(*logs)-1].BlockNumber)
+			rm.stateSyncBlockNumber = big.NewInt(blockNumber)
 
-			return NewRequest("eth_getLogs", []interface{}{filter}), nil
+			return nil
+		},
+	},
+	{
+		Key:      "StateSyncTx Scenario: eth_getLogs (Heimdall clerk event records)",
+		Tags:     []string{heimdallTag},
+		Requires: []string{"mostRecentBlockNumber", "chainId"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getLogs", []interface{}{stateCommittedFilter(rm.mostRecentBlockNumber)}), nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
 			logs, err := parseResponse[[]types.Log](resp.Result)
@@ -1504,17 +2364,30 @@ var testCases = []TestCase{
 			if len(*logs) == 0 {
 				return fmt.Errorf("must have at least one state sync event in this block range")
 			}
-			rm.stateSyncTxHash = (*logs)[len(*logs)-1].TxHash
-			rm.stateSyncBlockHash = (*logs)[len(*logs)-1].BlockHash
-
-			blockNumber := (int64)((*logs)[len(*logs)-1].BlockNumber)
-			rm.stateSyncBlockNumber = big.NewInt(blockNumber)
+			rm.stateSyncEvents = checkStateSyncEvents(rm.client, rm.heimdall, rm.chainId, *logs)
 
+			var mismatches, unverifiable int
+			for _, check := range rm.stateSyncEvents {
+				switch {
+				case errors.Is(check.Err, errStateSyncDataNotExposed):
+					unverifiable++
+				case check.Err != nil:
+					mismatches++
+				}
+			}
+			if mismatches > 0 {
+				return fmt.Errorf("%d of %d state sync events do not match their Heimdall event record", mismatches, len(rm.stateSyncEvents))
+			}
+			if unverifiable > 0 {
+				return fmt.Errorf("%d of %d state sync events could not be verified against their Heimdall event record: %w", unverifiable, len(rm.stateSyncEvents), errStateSyncDataNotExposed)
+			}
 			return nil
 		},
 	},
 	{
-		Key: "StateSyncTx Scenario: eth_getTransactionReceipt",
+		Key:      "StateSyncTx Scenario: eth_getTransactionReceipt",
+		Requires: []string{"stateSyncTxHash"},
+		Produces: []string{"stateSyncTxIndex"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			if (rm.stateSyncTxHash == common.Hash{}) {
 				return nil, fmt.Errorf("no state sync tx given for request")
@@ -1523,27 +2396,22 @@ var testCases = []TestCase{
 			return NewRequest("eth_getTransactionReceipt", []interface{}{rm.stateSyncTxHash}), nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
-			stateSyncTxReceipt, err := parseResponse[map[string]interface{}](resp.Result)
+			stateSyncTxReceipt, err := parseObject[RPCReceipt](resp.Result)
 			if err != nil {
 				return err
 			}
-			txIndexHexStringWithoutPrefix := (*stateSyncTxReceipt)["transactionIndex"].(string)[2:]
-			txIndex, err := strconv.ParseInt(txIndexHexStringWithoutPrefix, 16, 0)
-			if err != nil {
-				return fmt.Errorf("error converting hex to int:%s", err)
-			}
-			rm.stateSyncTxIndex = int(txIndex)
-
-			err = validateStateSyncTxReceipt(*stateSyncTxReceipt)
+			err = validateStateSyncTxReceipt(stateSyncTxReceipt)
 			if err != nil {
 				return err
 			}
+			rm.stateSyncTxIndex = int(*stateSyncTxReceipt.TransactionIndex)
 
 			return nil
 		},
 	},
 	{
-		Key: "StateSyncTx Scenario: eth_getTransactionByHash",
+		Key:      "StateSyncTx Scenario: eth_getTransactionByHash",
+		Requires: []string{"stateSyncTxHash", "stateSyncTxIndex"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			if (rm.stateSyncBlockHash == common.Hash{}) {
 				return nil, fmt.Errorf("no state sync tx given for request")
@@ -1558,6 +2426,9 @@ var testCases = []TestCase{
 			}
 
 			method := "eth_getTransactionByHash"
+			if tx.TransactionIndex == nil {
+				return fmt.Errorf("missing transactionIndex on method %s", method)
+			}
 			if int(*tx.TransactionIndex) != rm.stateSyncTxIndex {
 				return fmt.Errorf("error on state sync tx index in block %s: transaction index on method %s: %d | transaction index on method eth_getTransactionReceipt: %d", rm.stateSyncBlockHash, method, int(*tx.TransactionIndex), rm.stateSyncTxIndex)
 			}
@@ -1566,7 +2437,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "StateSyncTx Scenario: eth_getTransactionByBlockHashAndIndex",
+		Key:      "StateSyncTx Scenario: eth_getTransactionByBlockHashAndIndex",
+		Requires: []string{"stateSyncBlockHash", "stateSyncTxIndex"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			if (rm.stateSyncBlockHash == common.Hash{}) {
 				return nil, fmt.Errorf("no state sync tx given for request")
@@ -1581,6 +2453,9 @@ var testCases = []TestCase{
 			}
 
 			method := "eth_getTransactionByBlockHashAndIndex"
+			if tx.TransactionIndex == nil {
+				return fmt.Errorf("missing transactionIndex on method %s", method)
+			}
 			if int(*tx.TransactionIndex) != rm.stateSyncTxIndex {
 				return fmt.Errorf("error on state sync tx index in block %s: transaction index on method %s: %d | transaction index on method eth_getTransactionReceipt: %d", rm.stateSyncBlockHash, method, int(*tx.TransactionIndex), rm.stateSyncTxIndex)
 			}
@@ -1589,7 +2464,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "StateSyncTx Scenario: eth_getTransactionByBlockNumberAndIndex",
+		Key:      "StateSyncTx Scenario: eth_getTransactionByBlockNumberAndIndex",
+		Requires: []string{"stateSyncBlockNumber", "stateSyncTxIndex"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			if rm.stateSyncBlockNumber == nil {
 				return nil, fmt.Errorf("no state sync tx given for request")
@@ -1603,6 +2479,9 @@ var testCases = []TestCase{
 			}
 
 			method := "eth_getTransactionByBlockNumberAndIndex"
+			if tx.TransactionIndex == nil {
+				return fmt.Errorf("missing transactionIndex on method %s", method)
+			}
 			if int(*tx.TransactionIndex) != rm.stateSyncTxIndex {
 				return fmt.Errorf("error on state sync tx index in block %s: transaction index on method %s: %d | transaction index on method eth_getTransactionReceipt: %d", rm.stateSyncBlockHash, method, int(*tx.TransactionIndex), rm.stateSyncTxIndex)
 			}
@@ -1611,7 +2490,9 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "StateSyncTx Scenario: eth_getBlockReceipts",
+		Key:      "StateSyncTx Scenario: eth_getBlockReceipts",
+		Requires: []string{"stateSyncBlockHash", "stateSyncTxIndex"},
+		Produces: []string{"stateSyncExpectedBlockTransactionCount", "stateSyncBlockReceipts"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			if (rm.stateSyncBlockHash == common.Hash{}) {
 				return nil, fmt.Errorf("no state sync tx given for request")
@@ -1619,22 +2500,42 @@ var testCases = []TestCase{
 			return NewRequest("eth_getBlockReceipts", []interface{}{rm.stateSyncBlockHash}), nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
-			txReceipts, err := parseResponse[[]map[string]interface{}](resp.Result)
+			txReceipts, err := parseReceipts(resp.Result)
 			if err != nil {
 				return err
 			}
-			rm.stateSyncExpectedBlockTransactionCount = len(*txReceipts)
+			rm.stateSyncExpectedBlockTransactionCount = len(txReceipts)
 
 			err = handleGetStateSyncBlockReceipts(rm, "eth_getBlockReceipts", txReceipts)
 			if err != nil {
 				return err
 			}
+			rm.stateSyncBlockReceipts = txReceipts
 
 			return nil
 		},
 	},
 	{
-		Key: "StateSyncTx Scenario: eth_getBlockTransactionCountByNumber",
+		Key:      "StateSyncTx Scenario: eth_getBlockByHash",
+		Requires: []string{"stateSyncBlockHash", "stateSyncBlockReceipts"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getBlockByHash", []interface{}{rm.stateSyncBlockHash, true}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			block, err := parseObject[RPCBlock](resp.Result)
+			if err != nil {
+				return err
+			}
+			err = validateBlock(block)
+			if err != nil {
+				return err
+			}
+			return verifyBlockRoots(block, rm.stateSyncBlockReceipts)
+		},
+	},
+	{
+		Key:      "StateSyncTx Scenario: eth_getBlockTransactionCountByNumber",
+		Requires: []string{"stateSyncBlockNumber", "stateSyncExpectedBlockTransactionCount"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getBlockTransactionCountByNumber", []interface{}{fmt.Sprintf("0x%x", rm.stateSyncBlockNumber)}), nil
 		},
@@ -1656,7 +2557,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "StateSyncTx Scenario: eth_getBlockTransactionCountByHash",
+		Key:      "StateSyncTx Scenario: eth_getBlockTransactionCountByHash",
+		Requires: []string{"stateSyncBlockHash", "stateSyncExpectedBlockTransactionCount"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getBlockTransactionCountByHash", []interface{}{rm.stateSyncBlockHash}), nil
 		},
@@ -1678,14 +2580,17 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_sendRawTransaction",
+		Key:      "Create Transaction Scenario: eth_sendRawTransaction",
+		Requires: []string{"chainId", "accountNonce", "gasPrice"},
+		Produces: []string{"expectedRawTx", "pushedTxHash"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			rm.expectedRawTx = generateRawTransaction(
-				rm.account.nonce.Uint64(),
+				rm.nextNonce(),
 				rm.expectedGasToCreateTransaction.Uint64(),
 				rm.gasPrice,
 				generateInputForDeployTestContract(rm.expectedKeyToStoreInContract, rm.expectedValueToStoreInContract),
 				rm.account.key, rm.chainId)
+			rm.pushedTxSentAt = time.Now()
 			return NewRequest("eth_sendRawTransaction",
 					[]interface{}{rm.expectedRawTx}),
 				nil
@@ -1697,13 +2602,16 @@ var testCases = []TestCase{
 			}
 			rm.pushedTxHash = *txHash
 
-			// sleeps 30 seconds to wait until tx is available for next request
-			time.Sleep(30 * time.Second)
+			// waits until the tx is mined so it is available for the next requests
+			if _, err := rm.waitForInclusion(rm.pushedTxHash, rm.pushedTxSentAt); err != nil {
+				return err
+			}
 			return nil
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_createAccessList",
+		Key:      "Create Transaction Scenario: eth_createAccessList",
+		Produces: []string{"accessList"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_createAccessList",
 					[]interface{}{prepareEstimateGasRequest(rm.account, generateInputForDeployTestContract(rm.expectedKeyToStoreInContract, rm.expectedValueToStoreInContract))}),
@@ -1714,14 +2622,17 @@ var testCases = []TestCase{
 			if err != nil {
 				return err
 			}
-			if len(*accessListResult.Accesslist) == 0 {
+			if accessListResult.Accesslist == nil || len(*accessListResult.Accesslist) == 0 {
 				return fmt.Errorf("should return at least on access list")
 			}
+			rm.accessList = *accessListResult.Accesslist
+			rm.accessListGasUsed = uint64(accessListResult.GasUsed)
 			return nil
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_getRawTransactionByHash",
+		Key:      "Create Transaction Scenario: eth_getRawTransactionByHash",
+		Requires: []string{"pushedTxHash", "expectedRawTx"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getRawTransactionByHash",
 					[]interface{}{rm.pushedTxHash}),
@@ -1740,30 +2651,42 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_getTransactionReceipt",
+		Key:      "Create Transaction Scenario: eth_getTransactionReceipt",
+		Requires: []string{"pushedTxHash"},
+		Produces: []string{"pushedTxBlockNumber", "pushedTxBlockHash", "pushedTxTransactionIndex", "pushedTxDeployedContractAddress"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getTransactionReceipt",
 					[]interface{}{rm.pushedTxHash}),
 				nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
-			txReceipt, err := parseResponse[map[string]interface{}](resp.Result)
+			receipt, err := parseObject[RPCReceipt](resp.Result)
 			if err != nil {
 				return err
 			}
-			if *txReceipt == nil {
+			if receipt == nil {
 				return fmt.Errorf("no transaction pushed")
 			}
+			if err := checkRequiredFields("receipt",
+				responseField{"blockNumber", receipt.BlockNumber != nil},
+				responseField{"blockHash", receipt.BlockHash != nil},
+				responseField{"transactionIndex", receipt.TransactionIndex != nil},
+				responseField{"contractAddress", receipt.ContractAddress != nil},
+			); err != nil {
+				return err
+			}
 
-			rm.pushedTxBlockNumber, _ = hexStringToBigInt((*txReceipt)["blockNumber"].(string))
-			rm.pushedTxBlockHash = common.HexToHash((*txReceipt)["blockHash"].(string))
-			rm.pushedTxTransactionIndex, _ = hexStringToBigInt((*txReceipt)["transactionIndex"].(string))
-			rm.pushedTxDeployedContractAddress = common.HexToAddress((*txReceipt)["contractAddress"].(string))
+			rm.pushedTxBlockNumber = receipt.BlockNumber.ToInt()
+			rm.pushedTxBlockHash = *receipt.BlockHash
+			rm.pushedTxTransactionIndex = new(big.Int).SetUint64(uint64(*receipt.TransactionIndex))
+			rm.pushedTxDeployedContractAddress = *receipt.ContractAddress
 			return nil
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_getCode",
+		Key:      "Create Transaction Scenario: eth_getCode",
+		Requires: []string{"pushedTxDeployedContractAddress"},
+		Produces: []string{"pushedTxDeployedContractRuntimeCode"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getCode",
 					[]interface{}{rm.pushedTxDeployedContractAddress, "latest"}),
@@ -1801,7 +2724,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_call",
+		Key:      "Create Transaction Scenario: eth_call",
+		Requires: []string{"pushedTxDeployedContractAddress"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			txParams := map[string]interface{}{
 				"to":   fmt.Sprintf("%s", rm.pushedTxDeployedContractAddress),
@@ -1816,8 +2740,8 @@ var testCases = []TestCase{
 			if err != nil {
 				return err
 			}
-			value := new(big.Int)
-			if _, success := value.SetString((*hexStringStoredValue)[2:], 16); !success {
+			value, err := hexStringToBigInt(*hexStringStoredValue)
+			if err != nil {
 				return fmt.Errorf("failed to convert hex string to big.Int")
 			}
 			if value.Cmp(rm.expectedValueToStoreInContract) != 0 {
@@ -1827,7 +2751,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_getRawTransactionByBlockNumberAndIndex",
+		Key:      "Create Transaction Scenario: eth_getRawTransactionByBlockNumberAndIndex",
+		Requires: []string{"pushedTxBlockNumber", "pushedTxTransactionIndex", "expectedRawTx"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getRawTransactionByBlockNumberAndIndex",
 					[]interface{}{fmt.Sprintf("0x%x", rm.pushedTxBlockNumber), fmt.Sprintf("0x%x", rm.pushedTxTransactionIndex)}),
@@ -1846,7 +2771,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_getRawTransactionByBlockHashAndIndex",
+		Key:      "Create Transaction Scenario: eth_getRawTransactionByBlockHashAndIndex",
+		Requires: []string{"pushedTxBlockHash", "pushedTxTransactionIndex", "expectedRawTx"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getRawTransactionByBlockHashAndIndex",
 					[]interface{}{rm.pushedTxBlockHash, fmt.Sprintf("0x%x", rm.pushedTxTransactionIndex)}),
@@ -1865,7 +2791,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_getStorageAt",
+		Key:      "Create Transaction Scenario: eth_getStorageAt",
+		Requires: []string{"pushedTxDeployedContractAddress"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getStorageAt",
 					[]interface{}{rm.pushedTxDeployedContractAddress, "0x0", "latest"}),
@@ -1876,8 +2803,8 @@ var testCases = []TestCase{
 			if err != nil {
 				return err
 			}
-			slot0Value := new(big.Int)
-			if _, success := slot0Value.SetString((*hexStringOnSlot0)[2:], 16); !success {
+			slot0Value, err := hexStringToBigInt(*hexStringOnSlot0)
+			if err != nil {
 				return fmt.Errorf("failed to convert hex string to big.Int")
 			}
 			if slot0Value.Cmp(rm.expectedSlot0Value) != 0 {
@@ -1888,10 +2815,33 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_getProof",
+		Key:      "Create Transaction Scenario: eth_getBlockByNumber",
+		Requires: []string{"pushedTxBlockNumber"},
+		Produces: []string{"pushedTxBlockStateRoot"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getBlockByNumber", []interface{}{hexutil.EncodeBig(rm.pushedTxBlockNumber), false}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			block, err := parseObject[RPCBlock](resp.Result)
+			if err != nil {
+				return err
+			}
+			err = validateBlock(block)
+			if err != nil {
+				return err
+			}
+			rm.pushedTxBlockStateRoot = *block.StateRoot
+			return nil
+		},
+	},
+	{
+		Key:      "Create Transaction Scenario: eth_getProof",
+		Requires: []string{"pushedTxDeployedContractAddress", "pushedTxDeployedContractRuntimeCode", "pushedTxBlockNumber", "pushedTxBlockStateRoot"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			// proves the state right after the deployment: slot 0 and storedValues[key], the mapping being at slot 1
+			slots := []common.Hash{{}, mappingSlot(rm.expectedKeyToStoreInContract, 1)}
 			return NewRequest("eth_getProof",
-					[]interface{}{rm.pushedTxDeployedContractAddress, []string{"0x0000000000000000000000000000000000000000000000000000000000000000"}, "latest"}),
+					[]interface{}{rm.pushedTxDeployedContractAddress, slots, hexutil.EncodeBig(rm.pushedTxBlockNumber)}),
 				nil
 		},
 		HandleResponse: func(rm *ResponseMap, resp Response) error {
@@ -1908,16 +2858,68 @@ var testCases = []TestCase{
 			if len(accountResult.AccountProof) == 0 {
 				return fmt.Errorf("must not be an empty account proof array")
 			}
+			if err := verifyAccountProof(rm.pushedTxBlockStateRoot, accountResult); err != nil {
+				return err
+			}
 
-			if len(accountResult.StorageProof) == 0 {
-				return fmt.Errorf("must not be an empty storage proof array")
+			if len(accountResult.StorageProof) != 2 {
+				return fmt.Errorf("invalid storage proof array: expected 2 entries, actual %d", len(accountResult.StorageProof))
+			}
+			expectedValues := []*big.Int{rm.expectedSlot0Value, rm.expectedValueToStoreInContract}
+			for i, storageProof := range accountResult.StorageProof {
+				value, err := verifyStorageProof(accountResult.StorageHash, storageProof)
+				if err != nil {
+					return err
+				}
+				if value.Cmp(expectedValues[i]) != 0 {
+					return fmt.Errorf("invalid value of storage key %s: expected %s, actual %s", storageProof.Key, expectedValues[i], value)
+				}
 			}
 
 			return nil
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_newFilter",
+		Key:      "Create Transaction Scenario: eth_getProof (empty slots)",
+		Requires: []string{"pushedTxDeployedContractAddress", "pushedTxBlockNumber", "pushedTxBlockStateRoot"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			// slot 2 is past the contract variables and the mapping has no entry for this key
+			slots := []common.Hash{common.BigToHash(big.NewInt(2)), mappingSlot(rm.expectedKeyToStoreInContract+"-unset", 1)}
+			return NewRequest("eth_getProof",
+					[]interface{}{rm.pushedTxDeployedContractAddress, slots, hexutil.EncodeBig(rm.pushedTxBlockNumber)}),
+				nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			accountResult, err := parseResponse[accountResult](resp.Result)
+			if err != nil {
+				return err
+			}
+			if err := verifyAccountProof(rm.pushedTxBlockStateRoot, accountResult); err != nil {
+				return err
+			}
+			if accountResult.StorageHash == types.EmptyRootHash {
+				return fmt.Errorf("storageHash of contract %s must not be empty", rm.pushedTxDeployedContractAddress)
+			}
+			if len(accountResult.StorageProof) != 2 {
+				return fmt.Errorf("invalid storage proof array: expected 2 entries, actual %d", len(accountResult.StorageProof))
+			}
+			for _, storageProof := range accountResult.StorageProof {
+				// the proof must show that nothing is stored under the key
+				value, err := verifyStorageProof(accountResult.StorageHash, storageProof)
+				if err != nil {
+					return err
+				}
+				if value.Sign() != 0 {
+					return fmt.Errorf("storage key %s must be empty, actual %s", storageProof.Key, value)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "Create Transaction Scenario: eth_newFilter",
+		Requires: []string{"pushedTxBlockNumber", "pushedTxDeployedContractAddress"},
+		Produces: []string{"filterId"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			eventSignature := "ContractDeployed()"
 			eventTopic := crypto.Keccak256Hash([]byte(eventSignature))
@@ -1946,6 +2948,10 @@ var testCases = []TestCase{
 	},
 	{
 		Key: "Create Transaction Scenario: eth_newBlockFilter",
+		// Created once the transaction is mined and polled in a later batch, leaving time
+		// for a new block
+		Requires: []string{"pushedTxBlockNumber"},
+		Produces: []string{"blockFilterId"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_newBlockFilter",
 					[]interface{}{}),
@@ -1962,7 +2968,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_getFilterChanges (from eth_newFilter)",
+		Key:      "Create Transaction Scenario: eth_getFilterChanges (from eth_newFilter)",
+		Requires: []string{"filterId"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getFilterChanges",
 					[]interface{}{rm.filterId}),
@@ -1981,7 +2988,8 @@ var testCases = []TestCase{
 		},
 	},
 	{
-		Key: "Create Transaction Scenario: eth_getFilterChanges (from eth_newBlockFilter)",
+		Key:      "Create Transaction Scenario: eth_getFilterChanges (from eth_newBlockFilter)",
+		Requires: []string{"blockFilterId", "pushedTxBlockNumber"},
 		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
 			return NewRequest("eth_getFilterChanges",
 					[]interface{}{rm.blockFilterId}),
@@ -1999,4 +3007,328 @@ var testCases = []TestCase{
 			return nil
 		},
 	},
+	{
+		Key:      "DynamicFeeTx Scenario: eth_sendRawTransaction",
+		Requires: []string{"chainId", "accountNonce", "maxPriorityFeePerGas", "nextBaseFee"},
+		Produces: []string{"dynamicFeeTx"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			// leaves room for the base fee to double before the tx is mined
+			gasFeeCap := new(big.Int).Add(new(big.Int).Mul(rm.nextBaseFee, big.NewInt(2)), rm.maxPriorityFeePerGas)
+			rm.dynamicFeeTx = generateDynamicFeeTransaction(
+				rm.nextNonce(),
+				rm.expectedGasToCreateTransaction.Uint64(),
+				rm.maxPriorityFeePerGas,
+				gasFeeCap,
+				generateInputForDeployTestContract(rm.expectedKeyToStoreInContract, rm.expectedValueToStoreInContract),
+				rm.account.key, rm.chainId)
+			rm.dynamicFeeTxSentAt = time.Now()
+			return NewRequest("eth_sendRawTransaction", []interface{}{encodeRawTransaction(rm.dynamicFeeTx)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			txHash, err := parseResponse[common.Hash](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *txHash != rm.dynamicFeeTx.Hash() {
+				return fmt.Errorf("invalid tx hash: expected %s, actual %s", rm.dynamicFeeTx.Hash(), txHash)
+			}
+			if _, err := rm.waitForInclusion(*txHash, rm.dynamicFeeTxSentAt); err != nil {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "DynamicFeeTx Scenario: eth_getTransactionReceipt",
+		Requires: []string{"dynamicFeeTx"},
+		Produces: []string{"dynamicFeeTxBlockNumber", "dynamicFeeTxEffectiveGasPrice"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getTransactionReceipt", []interface{}{rm.dynamicFeeTx.Hash()}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			receipt, err := parseObject[RPCReceipt](resp.Result)
+			if err != nil {
+				return err
+			}
+			effectiveGasPrice, err := validateTypedTransactionReceipt(receipt, rm.dynamicFeeTx)
+			if err != nil {
+				return err
+			}
+			rm.dynamicFeeTxBlockNumber = receipt.BlockNumber.ToInt()
+			rm.dynamicFeeTxEffectiveGasPrice = effectiveGasPrice
+			return nil
+		},
+	},
+	{
+		Key:      "DynamicFeeTx Scenario: eth_getTransactionByHash",
+		Requires: []string{"dynamicFeeTx", "dynamicFeeTxBlockNumber", "dynamicFeeTxEffectiveGasPrice"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getTransactionByHash", []interface{}{rm.dynamicFeeTx.Hash()}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			tx, err := parseResponse[RPCTransaction](resp.Result)
+			if err != nil {
+				return err
+			}
+			if err := validateTypedTransaction(tx, rm.dynamicFeeTx); err != nil {
+				return err
+			}
+			if tx.BlockNumber == nil || tx.BlockNumber.ToInt().Cmp(rm.dynamicFeeTxBlockNumber) != 0 {
+				return fmt.Errorf("invalid blockNumber: expected %s, actual %v", rm.dynamicFeeTxBlockNumber, tx.BlockNumber)
+			}
+			// gasPrice of a mined dynamic fee tx is its effective gas price
+			if tx.GasPrice == nil || tx.GasPrice.ToInt().Cmp(rm.dynamicFeeTxEffectiveGasPrice) != 0 {
+				return fmt.Errorf("invalid gasPrice: expected effective gas price %s, actual %v", rm.dynamicFeeTxEffectiveGasPrice, tx.GasPrice)
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "DynamicFeeTx Scenario: eth_getBlockByNumber",
+		Requires: []string{"dynamicFeeTx", "dynamicFeeTxBlockNumber", "dynamicFeeTxEffectiveGasPrice"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getBlockByNumber", []interface{}{hexutil.EncodeBig(rm.dynamicFeeTxBlockNumber), false}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			block, err := parseResponse[blockFees](resp.Result)
+			if err != nil {
+				return err
+			}
+			if block.BaseFee == nil {
+				return fmt.Errorf("baseFeePerGas must be set on block %s", rm.dynamicFeeTxBlockNumber)
+			}
+			found := false
+			for _, txHash := range block.Transactions {
+				if txHash == rm.dynamicFeeTx.Hash() {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("transaction %s not found in block %s", rm.dynamicFeeTx.Hash(), rm.dynamicFeeTxBlockNumber)
+			}
+
+			// effective gas price = min(maxFeePerGas, baseFeePerGas + maxPriorityFeePerGas)
+			expected := new(big.Int).Add(block.BaseFee.ToInt(), rm.dynamicFeeTx.GasTipCap())
+			if expected.Cmp(rm.dynamicFeeTx.GasFeeCap()) > 0 {
+				expected = rm.dynamicFeeTx.GasFeeCap()
+			}
+			if expected.Cmp(rm.dynamicFeeTxEffectiveGasPrice) != 0 {
+				return fmt.Errorf("invalid effective gas price: expected %s from base fee %s, actual %s", expected, block.BaseFee.ToInt(), rm.dynamicFeeTxEffectiveGasPrice)
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "AccessListTx Scenario: eth_sendRawTransaction",
+		Requires: []string{"chainId", "accountNonce", "gasPrice", "accessList"},
+		Produces: []string{"accessListTx"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			// gasUsed returned by eth_createAccessList already prices the access list, keep a margin on top
+			gasLimit := rm.accessListGasUsed + rm.accessListGasUsed/4
+			rm.accessListTx = generateAccessListTransaction(
+				rm.nextNonce(),
+				gasLimit,
+				rm.gasPrice,
+				rm.accessList,
+				generateInputForDeployTestContract(rm.expectedKeyToStoreInContract, rm.expectedValueToStoreInContract),
+				rm.account.key, rm.chainId)
+			rm.accessListTxSentAt = time.Now()
+			return NewRequest("eth_sendRawTransaction", []interface{}{encodeRawTransaction(rm.accessListTx)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			txHash, err := parseResponse[common.Hash](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *txHash != rm.accessListTx.Hash() {
+				return fmt.Errorf("invalid tx hash: expected %s, actual %s", rm.accessListTx.Hash(), txHash)
+			}
+			if _, err := rm.waitForInclusion(*txHash, rm.accessListTxSentAt); err != nil {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "AccessListTx Scenario: eth_getTransactionReceipt",
+		Requires: []string{"accessListTx"},
+		Produces: []string{"accessListTxEffectiveGasPrice"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getTransactionReceipt", []interface{}{rm.accessListTx.Hash()}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			receipt, err := parseObject[RPCReceipt](resp.Result)
+			if err != nil {
+				return err
+			}
+			effectiveGasPrice, err := validateTypedTransactionReceipt(receipt, rm.accessListTx)
+			if err != nil {
+				return err
+			}
+			rm.accessListTxEffectiveGasPrice = effectiveGasPrice
+			return nil
+		},
+	},
+	{
+		Key:      "AccessListTx Scenario: eth_getTransactionByHash",
+		Requires: []string{"accessListTx", "accessListTxEffectiveGasPrice"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getTransactionByHash", []interface{}{rm.accessListTx.Hash()}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			tx, err := parseResponse[RPCTransaction](resp.Result)
+			if err != nil {
+				return err
+			}
+			if err := validateTypedTransaction(tx, rm.accessListTx); err != nil {
+				return err
+			}
+			if tx.GasPrice == nil || tx.GasPrice.ToInt().Cmp(rm.accessListTxEffectiveGasPrice) != 0 {
+				return fmt.Errorf("invalid gasPrice: expected effective gas price %s, actual %v", rm.accessListTxEffectiveGasPrice, tx.GasPrice)
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "Subscription Scenario: eth_subscribe (newHeads)",
+		Produces: []string{"newHeadsSubscriptionId"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_subscribe", []interface{}{"newHeads"}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			subscriptionId, err := parseResponse[string](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *subscriptionId == "" {
+				return fmt.Errorf("empty subscription id")
+			}
+			rm.newHeadsSubscriptionId = *subscriptionId
+			return nil
+		},
+	},
+	{
+		Key:      "Subscription Scenario: eth_subscribe (logs)",
+		Produces: []string{"logsSubscriptionId"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			eventTopic := crypto.Keccak256Hash([]byte("ContractDeployed()"))
+			filter := map[string]interface{}{
+				"topics": [][]common.Hash{{eventTopic}},
+			}
+			return NewRequest("eth_subscribe", []interface{}{"logs", filter}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			subscriptionId, err := parseResponse[string](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *subscriptionId == "" {
+				return fmt.Errorf("empty subscription id")
+			}
+			rm.logsSubscriptionId = *subscriptionId
+			return nil
+		},
+	},
+	{
+		Key:      "Subscription Scenario: eth_subscribe (newPendingTransactions)",
+		Produces: []string{"pendingTxSubscriptionId"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_subscribe", []interface{}{"newPendingTransactions"}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			subscriptionId, err := parseResponse[string](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *subscriptionId == "" {
+				return fmt.Errorf("empty subscription id")
+			}
+			rm.pendingTxSubscriptionId = *subscriptionId
+			return nil
+		},
+	},
+	{
+		Key:      "Subscription Scenario: eth_sendRawTransaction",
+		Requires: []string{"chainId", "accountNonce", "gasPrice", "newHeadsSubscriptionId", "logsSubscriptionId", "pendingTxSubscriptionId"},
+		Produces: []string{"subscriptionTxHash", "subscriptionTxContractAddress"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			nonce := rm.nextNonce()
+			rawTx := generateRawTransaction(
+				nonce,
+				rm.expectedGasToCreateTransaction.Uint64(),
+				rm.gasPrice,
+				generateInputForDeployTestContract(rm.expectedKeyToStoreInContract, rm.expectedValueToStoreInContract),
+				rm.account.key, rm.chainId)
+			rm.subscriptionTxHash = crypto.Keccak256Hash(common.FromHex(rawTx))
+			rm.subscriptionTxContractAddress = crypto.CreateAddress(rm.account.addr, nonce)
+			return NewRequest("eth_sendRawTransaction", []interface{}{rawTx}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			txHash, err := parseResponse[common.Hash](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *txHash != rm.subscriptionTxHash {
+				return fmt.Errorf("invalid tx hash: expected %s, actual %s", rm.subscriptionTxHash, txHash)
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "Subscription Scenario: eth_unsubscribe (newPendingTransactions)",
+		Requires: []string{"pendingTxSubscriptionId", "subscriptionTxHash"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			_, err := waitForNotification(rm.subscriber, rm.pendingTxSubscriptionId, subscriptionNotificationTimeout, func(txHash common.Hash) bool {
+				return txHash == rm.subscriptionTxHash
+			})
+			if err != nil {
+				return nil, fmt.Errorf("pending transaction %s not notified: %w", rm.subscriptionTxHash, err)
+			}
+			return NewRequest("eth_unsubscribe", []interface{}{rm.pendingTxSubscriptionId}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			return validateUnsubscribe(resp)
+		},
+	},
+	{
+		Key:      "Subscription Scenario: eth_unsubscribe (logs)",
+		Requires: []string{"logsSubscriptionId", "subscriptionTxHash", "subscriptionTxContractAddress"},
+		Produces: []string{"subscriptionTxBlockHash"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			logEvent, err := waitForNotification(rm.subscriber, rm.logsSubscriptionId, subscriptionNotificationTimeout, func(logEvent types.Log) bool {
+				return logEvent.TxHash == rm.subscriptionTxHash
+			})
+			if err != nil {
+				return nil, fmt.Errorf("ContractDeployed log of tx %s not notified: %w", rm.subscriptionTxHash, err)
+			}
+			if logEvent.Address != rm.subscriptionTxContractAddress {
+				return nil, fmt.Errorf("invalid log address: expected %s, actual %s", rm.subscriptionTxContractAddress, logEvent.Address)
+			}
+			if logEvent.Removed {
+				return nil, fmt.Errorf("log of tx %s was removed by a reorg", rm.subscriptionTxHash)
+			}
+			rm.subscriptionTxBlockHash = logEvent.BlockHash
+			return NewRequest("eth_unsubscribe", []interface{}{rm.logsSubscriptionId}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			return validateUnsubscribe(resp)
+		},
+	},
+	{
+		Key:      "Subscription Scenario: eth_unsubscribe (newHeads)",
+		Requires: []string{"newHeadsSubscriptionId", "subscriptionTxBlockHash"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			_, err := waitForNotification(rm.subscriber, rm.newHeadsSubscriptionId, subscriptionNotificationTimeout, func(header headNotification) bool {
+				return header.Hash == rm.subscriptionTxBlockHash
+			})
+			if err != nil {
+				return nil, fmt.Errorf("head %s containing tx %s not notified: %w", rm.subscriptionTxBlockHash, rm.subscriptionTxHash, err)
+			}
+			return NewRequest("eth_unsubscribe", []interface{}{rm.newHeadsSubscriptionId}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			return validateUnsubscribe(resp)
+		},
+	},
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"fakenode"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestRunTestCases(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	chain.AddBlocks(12, "0x00000000000000000000000000000000000000aa")
+	bor := fakenode.NewBor(chain)
+	defer bor.Close()
+	client := newTestHTTPClient(bor.URL)
+
+	testCases := []TestCase{
+		{
+			Key:      "eth_blockNumber",
+			Produces: []string{"mostRecentBlockNumber"},
+			PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+				return NewRequest("eth_blockNumber", []interface{}{}), nil
+			},
+			HandleResponse: func(rm *ResponseMap, resp Response) error {
+				number, err := parseResponse[hexutil.Big](resp.Result)
+				if err != nil {
+					return err
+				}
+				rm.mostRecentBlockNumber = number.ToInt()
+				return nil
+			},
+		},
+		{
+			Key:      "eth_getBlockByNumber",
+			Requires: []string{"mostRecentBlockNumber"},
+			PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+				return NewRequest("eth_getBlockByNumber", []interface{}{hexutil.EncodeBig(rm.mostRecentBlockNumber), false}), nil
+			},
+			HandleResponse: func(rm *ResponseMap, resp Response) error {
+				block, err := parseResponse[struct {
+					Number *hexutil.Big `json:"number"`
+				}](resp.Result)
+				if err != nil {
+					return err
+				}
+				if block.Number.ToInt().Cmp(big.NewInt(12)) != 0 {
+					return fmt.Errorf("expected block 12, got %s", block.Number)
+				}
+				return nil
+			},
+		},
+		{
+			Key:         "unknown method",
+			ExpectError: &ExpectedError{Code: -32601, Message: "does not exist"},
+			PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+				return NewRequest("eth_rpcTestsUnknownMethod", []interface{}{}), nil
+			},
+		},
+		{
+			Key:         "malformed JSON",
+			ExpectError: &ExpectedError{Code: -32700},
+			PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+				return &Request{Raw: `{"jsonrpc":"2.0"`}, nil
+			},
+		},
+		{
+			Key:      "eth_chainId",
+			Produces: []string{"chainId"},
+			PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+				return NewRequest("eth_chainId", []interface{}{}), nil
+			},
+			HandleResponse: func(rm *ResponseMap, resp Response) error {
+				return errors.New("rejected")
+			},
+		},
+		{
+			Key:      "depends on eth_chainId",
+			Requires: []string{"chainId"},
+			PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+				return NewRequest("eth_chainId", []interface{}{}), nil
+			},
+		},
+	}
+
+	batches, err := scheduleTestCases(testCases)
+	if err != nil {
+		t.Fatalf("scheduleTestCases: %v", err)
+	}
+	results, _ := runTestCases(client, batches, &ResponseMap{client: client})
+
+	expected := map[string]TestStatus{
+		"eth_blockNumber":        TestStatusPassed,
+		"eth_getBlockByNumber":   TestStatusPassed,
+		"unknown method":         TestStatusPassed,
+		"malformed JSON":         TestStatusPassed,
+		"eth_chainId":            TestStatusFailed,
+		"depends on eth_chainId": TestStatusSkipped,
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for _, result := range results {
+		if result.Status != expected[result.Key] {
+			t.Errorf("%s: expected %s, got %s (%v)", result.Key, expected[result.Key], result.Status, result.Err)
+		}
+	}
+}
+
+func TestGenerateAccountsUsingMnemonic(t *testing.T) {
+	accounts := generateAccountsUsingMnemonic("test test test test test test test test test test test junk", 3)
+	if len(accounts) != 3 {
+		t.Fatalf("expected 3 accounts, got %d", len(accounts))
+	}
+	for i, expected := range []string{
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		"0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		"0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	} {
+		if accounts[i].addr.Hex() != expected {
+			t.Errorf("account %d: expected %s, got %s", i, expected, accounts[i].addr.Hex())
+		}
+	}
+}
+
+func TestMeasureLatenciesSkipsNegativeTestCases(t *testing.T) {
+	chain := fakenode.NewChain(137)
+	chain.AddBlocks(1, "0x00000000000000000000000000000000000000aa")
+	bor := fakenode.NewBor(chain)
+	defer bor.Close()
+	client := newTestHTTPClient(bor.URL)
+
+	latencies := measureLatencies(client, []TestResult{
+		{Key: "eth_blockNumber", Status: TestStatusPassed, Req: NewRequest("eth_blockNumber", []interface{}{})},
+		{Key: "eth_blockNumber (too many params)", Status: TestStatusPassed, ExpectsError: true, Req: NewRequest("eth_blockNumber", []interface{}{"0x1"})},
+		{Key: "unknown method", Status: TestStatusPassed, ExpectsError: true, Req: NewRequest("eth_rpcTestsUnknownMethod", []interface{}{})},
+	}, 3)
+	if len(latencies) != 1 || latencies[0].Method != "eth_blockNumber" || latencies[0].Samples != 3 || latencies[0].Errors != 0 {
+		t.Errorf("expected 3 samples of eth_blockNumber without errors, got %+v", latencies)
+	}
+}
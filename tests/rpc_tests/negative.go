@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// JSON-RPC error codes returned by Bor, as defined by the JSON-RPC 2.0 specification and
+// go-ethereum.
+const (
+	errCodeParseError      = -32700
+	errCodeInvalidRequest  = -32600
+	errCodeMethodNotFound  = -32601
+	errCodeInvalidParams   = -32602
+	errCodeServerError     = -32000
+	errCodeExecutionRevert = 3
+)
+
+const (
+	// revertReason is the reason of the revert triggered by revertingInitCode.
+	revertReason = "rpc-tests"
+	// queuedNonceGap is how far ahead of the account nonce the queued transaction of the
+	// negative scenario is sent, so that it is never mined. It is dropped from the pool
+	// once its queue lifetime expires.
+	queuedNonceGap = 1000
+	// beyondHeadBlocks is how far beyond the most recent block the negative scenario queries.
+	beyondHeadBlocks = 1000
+)
+
+// revertingInitCode returns contract creation code reverting with Error(revertReason),
+// along with the revert data.
+func revertingInitCode() (code []byte, revertData []byte) {
+	revertData = append(revertData, crypto.Keccak256([]byte("Error(string)"))[:4]...)
+	revertData = append(revertData, common.LeftPadBytes([]byte{0x20}, 32)...)
+	revertData = append(revertData, common.LeftPadBytes([]byte{byte(len(revertReason))}, 32)...)
+	revertData = append(revertData, common.RightPadBytes([]byte(revertReason), 32)...)
+
+	// CODECOPY the revert data appended to the code into memory, then REVERT with it
+	size := byte(len(revertData))
+	code = []byte{0x60, size, 0x60, 12, 0x60, 0x00, 0x39, 0x60, size, 0x60, 0x00, 0xfd}
+	return append(code, revertData...), revertData
+}
+
+// transferTransaction signs a transfer of value from account to the given address.
+func transferTransaction(nonce uint64, to common.Address, value *big.Int, gasPrice *big.Int, account Account, chainID *big.Int) *types.Transaction {
+	return signTransaction(&types.LegacyTx{
+		Nonce:    nonce,
+		To:       &to,
+		Value:    value,
+		Gas:      21000,
+		GasPrice: gasPrice,
+	}, account.key, chainID)
+}
+
+// negativeTestCases check the errors returned by Bor on invalid calls, so that changes
+// to its error semantics are caught.
+var negativeTestCases = []TestCase{
+	{
+		Key:         "Negative Scenario: unknown method",
+		ExpectError: &ExpectedError{Code: errCodeMethodNotFound, Message: "does not exist"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_rpcTestsUnknownMethod", []interface{}{}), nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: malformed JSON",
+		ExpectError: &ExpectedError{Code: errCodeParseError, Message: "parse error"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return &Request{Raw: `{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1`}, nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: request without method",
+		ExpectError: &ExpectedError{Code: errCodeInvalidRequest, Message: "invalid request"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return &Request{Raw: `{"jsonrpc":"2.0","params":[],"id":1}`}, nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: eth_getBalance (invalid address)",
+		ExpectError: &ExpectedError{Code: errCodeInvalidParams, Message: "^invalid argument 0"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getBalance", []interface{}{"0x1234", "latest"}), nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: eth_getBalance (missing params)",
+		ExpectError: &ExpectedError{Code: errCodeInvalidParams, Message: "missing value for required argument 0"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getBalance", []interface{}{}), nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: eth_blockNumber (too many params)",
+		ExpectError: &ExpectedError{Code: errCodeInvalidParams, Message: "too many arguments"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_blockNumber", []interface{}{"latest"}), nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: eth_getBlockByNumber (invalid block tag)",
+		ExpectError: &ExpectedError{Code: errCodeInvalidParams, Message: "^invalid argument 0"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getBlockByNumber", []interface{}{"newest", false}), nil
+		},
+	},
+	{
+		Key:      "Negative Scenario: eth_getBlockByNumber (block beyond head)",
+		Requires: []string{"mostRecentBlockNumber"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			number := new(big.Int).Add(rm.mostRecentBlockNumber, big.NewInt(beyondHeadBlocks))
+			return NewRequest("eth_getBlockByNumber", []interface{}{hexutil.EncodeBig(number), false}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			if !bytes.Equal(resp.Result, []byte("null")) {
+				return fmt.Errorf("expected null for a block beyond head, got %s", resp.Result)
+			}
+			return nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: eth_getBalance (block beyond head)",
+		Requires:    []string{"mostRecentBlockNumber"},
+		ExpectError: &ExpectedError{Code: errCodeServerError, Message: "header not found"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			number := new(big.Int).Add(rm.mostRecentBlockNumber, big.NewInt(beyondHeadBlocks))
+			return NewRequest("eth_getBalance", []interface{}{rm.account.addr, hexutil.EncodeBig(number)}), nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: eth_call (revert with data)",
+		ExpectError: &ExpectedError{Code: errCodeExecutionRevert, Message: "^execution reverted: " + revertReason + "$"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			code, _ := revertingInitCode()
+			call := map[string]interface{}{
+				"from": rm.account.addr,
+				"data": hexutil.Bytes(code),
+			}
+			return NewRequest("eth_call", []interface{}{call, "latest"}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			data, err := parseResponse[hexutil.Bytes](resp.Error.Data)
+			if err != nil {
+				return fmt.Errorf("invalid revert data: %w", err)
+			}
+			_, expected := revertingInitCode()
+			if !bytes.Equal(*data, expected) {
+				return fmt.Errorf("invalid revert data: expected %s, got %s", hexutil.Bytes(expected), data)
+			}
+			return nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: eth_sendRawTransaction (nonce too low)",
+		Requires:    []string{"chainId", "gasPrice", "pushedTxBlockNumber"},
+		ExpectError: &ExpectedError{Code: errCodeServerError, Message: "^nonce too low"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			// The account has at least the transaction of the Create Transaction Scenario mined
+			rawTx := encodeRawTransaction(transferTransaction(0, rm.account.addr, big.NewInt(0), rm.gasPrice, rm.account, rm.chainId))
+			return NewRequest("eth_sendRawTransaction", []interface{}{rawTx}), nil
+		},
+	},
+	{
+		Key:         "Negative Scenario: eth_sendRawTransaction (insufficient funds)",
+		Requires:    []string{"chainId", "gasPrice"},
+		ExpectError: &ExpectedError{Code: errCodeServerError, Message: "^insufficient funds"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			key, err := randomness.generateKey()
+			if err != nil {
+				return nil, err
+			}
+			emptyAccount := Account{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}
+			rawTx := encodeRawTransaction(transferTransaction(0, emptyAccount.addr, big.NewInt(1), rm.gasPrice, emptyAccount, rm.chainId))
+			return NewRequest("eth_sendRawTransaction", []interface{}{rawTx}), nil
+		},
+	},
+	{
+		Key:      "Negative Scenario: eth_sendRawTransaction (queued transaction)",
+		Requires: []string{"chainId", "gasPrice", "accountNonce"},
+		Produces: []string{"queuedTxNonce"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			rm.queuedTxNonce = rm.nonces.peek(rm.account.addr) + queuedNonceGap
+			rawTx := encodeRawTransaction(transferTransaction(rm.queuedTxNonce, rm.account.addr, big.NewInt(0), rm.gasPrice, rm.account, rm.chainId))
+			return NewRequest("eth_sendRawTransaction", []interface{}{rawTx}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			_, err := parseResponse[common.Hash](resp.Result)
+			return err
+		},
+	},
+	{
+		Key:         "Negative Scenario: eth_sendRawTransaction (replacement underpriced)",
+		Requires:    []string{"chainId", "gasPrice", "queuedTxNonce"},
+		ExpectError: &ExpectedError{Code: errCodeServerError, Message: "^replacement transaction underpriced"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			// Same nonce and gas price as the queued transaction, but a different value
+			rawTx := encodeRawTransaction(transferTransaction(rm.queuedTxNonce, rm.account.addr, big.NewInt(1), rm.gasPrice, rm.account, rm.chainId))
+			return NewRequest("eth_sendRawTransaction", []interface{}{rawTx}), nil
+		},
+	},
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxNonceRetries is how many times a transaction rejected with "nonce too low" is
+// signed again with the nonce the node expects.
+const maxNonceRetries = 3
+
+// senderNonces are the nonces of a sender account: pending is the next one to send a
+// transaction with, confirmed the next one the chain expects, i.e. the transactions
+// below it are known to be mined.
+type senderNonces struct {
+	pending   uint64
+	confirmed uint64
+}
+
+// nonceManager hands out the nonces of the sender accounts, so that scenarios can send
+// transactions from many senders at once, and several from each sender, without
+// waiting for the previous ones to be mined.
+type nonceManager struct {
+	mu      sync.Mutex
+	senders Accounts
+	nonces  map[common.Address]*senderNonces
+}
+
+func newNonceManager(senders Accounts) *nonceManager {
+	nm := &nonceManager{senders: senders, nonces: make(map[common.Address]*senderNonces)}
+	for _, sender := range senders {
+		nm.nonces[sender.addr] = &senderNonces{}
+	}
+	return nm
+}
+
+// get returns the nonces of addr, which must be called with nm.mu held.
+func (nm *nonceManager) get(addr common.Address) *senderNonces {
+	nonces, ok := nm.nonces[addr]
+	if !ok {
+		nonces = &senderNonces{}
+		nm.nonces[addr] = nonces
+	}
+	return nonces
+}
+
+// sync sets the nonces of addr from its transaction count at the latest block. The
+// nonces already reserved beyond it are kept, their transactions may not be mined yet.
+func (nm *nonceManager) sync(addr common.Address, count uint64) {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	nonces := nm.get(addr)
+	nonces.confirmed = max(nonces.confirmed, count)
+	nonces.pending = max(nonces.pending, count)
+}
+
+// reserve returns the next nonce of addr and reserves it.
+func (nm *nonceManager) reserve(addr common.Address) uint64 {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	nonces := nm.get(addr)
+	nonce := nonces.pending
+	nonces.pending++
+	return nonce
+}
+
+// peek returns the next nonce of addr without reserving it.
+func (nm *nonceManager) peek(addr common.Address) uint64 {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	return nm.get(addr).pending
+}
+
+// confirm records that the transaction of addr with nonce was mined.
+func (nm *nonceManager) confirm(addr common.Address, nonce uint64) {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	nonces := nm.get(addr)
+	nonces.confirmed = max(nonces.confirmed, nonce+1)
+	nonces.pending = max(nonces.pending, nonces.confirmed)
+}
+
+// confirmed returns the next nonce the chain expects from addr, as far as the mined
+// transactions tell.
+func (nm *nonceManager) confirmed(addr common.Address) uint64 {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	return nm.get(addr).confirmed
+}
+
+// recover moves the pending nonce of addr to the transaction count of the node, pending
+// transactions included, once a transaction was rejected with "nonce too low", e.g.
+// because the account sent transactions the manager does not know about.
+func (nm *nonceManager) recover(client RPCClient, addr common.Address) error {
+	count, err := fetchTransactionCount(client, addr, "pending")
+	if err != nil {
+		return fmt.Errorf("fetching the nonce of %s: %w", addr, err)
+	}
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	nonces := nm.get(addr)
+	nonces.pending = max(nonces.pending, count)
+	return nil
+}
+
+func fetchTransactionCount(client RPCClient, addr common.Address, block string) (uint64, error) {
+	responses, err := client.Call([]Request{*NewRequest("eth_getTransactionCount", []interface{}{addr, block})})
+	if err != nil {
+		return 0, err
+	}
+	if len(responses) != 1 {
+		return 0, fmt.Errorf("expected 1 response, got %d", len(responses))
+	}
+	if responses[0].Error != nil {
+		return 0, fmt.Errorf("request error; message: %s | code: %d", responses[0].Error.Message, responses[0].Error.Code)
+	}
+	count, err := parseResponse[hexutil.Uint64](responses[0].Result)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(*count), nil
+}
+
+// isNonceTooLow tells whether the node rejected a transaction because its nonce is
+// already used.
+func isNonceTooLow(err *RPCError) bool {
+	return err != nil && err.Code == errCodeServerError && strings.HasPrefix(err.Message, "nonce too low")
+}
+
+// sendTransaction signs the transaction build returns for the next nonce of from and
+// sends it. When the node rejects the nonce as too low, the transaction is signed again
+// with the nonce the node expects.
+func (nm *nonceManager) sendTransaction(client RPCClient, from Account, build func(nonce uint64) *types.Transaction) (*types.Transaction, error) {
+	for attempt := 0; ; attempt++ {
+		tx := build(nm.reserve(from.addr))
+		responses, err := client.Call([]Request{*NewRequest("eth_sendRawTransaction", []interface{}{encodeRawTransaction(tx)})})
+		if err != nil {
+			return nil, err
+		}
+		if len(responses) != 1 {
+			return nil, fmt.Errorf("expected 1 response, got %d", len(responses))
+		}
+		if rpcErr := responses[0].Error; rpcErr != nil {
+			if !isNonceTooLow(rpcErr) || attempt == maxNonceRetries {
+				return nil, fmt.Errorf("sending nonce %d of %s: %s (code %d)", tx.Nonce(), from.addr, rpcErr.Message, rpcErr.Code)
+			}
+			if err := nm.recover(client, from.addr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		txHash, err := parseResponse[common.Hash](responses[0].Result)
+		if err != nil {
+			return nil, err
+		}
+		if *txHash != tx.Hash() {
+			return nil, fmt.Errorf("invalid tx hash: expected %s, actual %s", tx.Hash(), txHash)
+		}
+		return tx, nil
+	}
+}
+
+// sendConcurrently sends count transactions from every sender, each sender from its own
+// goroutine, and returns the transactions sent by sender. A sender stops at its first
+// failed transaction, since the following ones would be stuck behind its nonce.
+func (nm *nonceManager) sendConcurrently(client RPCClient, senders Accounts, count int, build func(from Account, nonce uint64) *types.Transaction) (map[common.Address][]*types.Transaction, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	sent := make(map[common.Address][]*types.Transaction, len(senders))
+	for _, sender := range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				tx, err := nm.sendTransaction(client, sender, func(nonce uint64) *types.Transaction {
+					return build(sender, nonce)
+				})
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else {
+					sent[sender.addr] = append(sent[sender.addr], tx)
+				}
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return sent, errors.Join(errs...)
+}
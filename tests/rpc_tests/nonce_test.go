@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"fakenode"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeTxPool accepts the transactions sent to a fake Bor node the way Bor checks their
+// nonce, every accepted transaction being mined right away in a block of its own.
+type fakeTxPool struct {
+	mu      sync.Mutex
+	chainID *big.Int
+	nonces  map[common.Address]uint64
+	mined   []*types.Transaction
+}
+
+func newFakeTxPool(chainID *big.Int) *fakeTxPool {
+	return &fakeTxPool{chainID: chainID, nonces: make(map[common.Address]uint64)}
+}
+
+func (p *fakeTxPool) sendRawTransaction(params []json.RawMessage) (interface{}, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(params[0], &raw); err != nil {
+		return nil, &fakenode.Error{Code: errCodeInvalidParams, Message: err.Error()}
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, &fakenode.Error{Code: errCodeInvalidParams, Message: err.Error()}
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(p.chainID), tx)
+	if err != nil {
+		return nil, &fakenode.Error{Code: errCodeInvalidParams, Message: err.Error()}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if next := p.nonces[from]; tx.Nonce() < next {
+		return nil, &fakenode.Error{Code: errCodeServerError, Message: fmt.Sprintf("nonce too low: next nonce %d, tx nonce %d", next, tx.Nonce())}
+	}
+	p.nonces[from] = tx.Nonce() + 1
+	p.mined = append(p.mined, tx)
+	return tx.Hash(), nil
+}
+
+func (p *fakeTxPool) getTransactionReceipt(params []json.RawMessage) (interface{}, error) {
+	var txHash common.Hash
+	if err := json.Unmarshal(params[0], &txHash); err != nil {
+		return nil, &fakenode.Error{Code: errCodeInvalidParams, Message: err.Error()}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, tx := range p.mined {
+		if tx.Hash() == txHash {
+			return map[string]interface{}{
+				"transactionHash": txHash,
+				"blockNumber":     hexutil.Uint64(i + 1),
+				"status":          hexutil.Uint64(types.ReceiptStatusSuccessful),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *fakeTxPool) getTransactionCount(params []json.RawMessage) (interface{}, error) {
+	var addr common.Address
+	if err := json.Unmarshal(params[0], &addr); err != nil {
+		return nil, &fakenode.Error{Code: errCodeInvalidParams, Message: err.Error()}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return hexutil.Uint64(p.nonces[addr]), nil
+}
+
+func TestNonceManager(t *testing.T) {
+	chainID := big.NewInt(137)
+	pool := newFakeTxPool(chainID)
+	bor := fakenode.NewBor(fakenode.NewChain(chainID.Uint64()))
+	defer bor.Close()
+	bor.Handle("eth_sendRawTransaction", pool.sendRawTransaction)
+	bor.Handle("eth_getTransactionCount", pool.getTransactionCount)
+	client := newTestHTTPClient(bor.URL)
+
+	keys := newRunRandomness(1)
+	var senders Accounts
+	for i := 0; i < 4; i++ {
+		key, err := keys.generateKey()
+		if err != nil {
+			t.Fatalf("generateKey: %v", err)
+		}
+		senders = append(senders, Account{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)})
+	}
+	nm := newNonceManager(senders)
+	transfer := func(from Account, nonce uint64) *types.Transaction {
+		return transferTransaction(nonce, from.addr, big.NewInt(0), big.NewInt(1), from, chainID)
+	}
+
+	// The first sender sent transactions the manager does not know about
+	pool.nonces[senders[0].addr] = 2
+	sent, err := nm.sendConcurrently(client, senders, 3, transfer)
+	if err != nil {
+		t.Fatalf("sendConcurrently: %v", err)
+	}
+	for i, sender := range senders {
+		first := uint64(0)
+		if i == 0 {
+			first = 2
+		}
+		txs := sent[sender.addr]
+		if len(txs) != 3 {
+			t.Fatalf("sender %d: expected 3 transactions, got %d", i, len(txs))
+		}
+		for j, tx := range txs {
+			if tx.Nonce() != first+uint64(j) {
+				t.Errorf("sender %d: expected nonce %d for transaction %d, got %d", i, first+uint64(j), j, tx.Nonce())
+			}
+			nm.confirm(sender.addr, tx.Nonce())
+		}
+		if confirmed := nm.confirmed(sender.addr); confirmed != first+3 || nm.peek(sender.addr) != confirmed {
+			t.Errorf("sender %d: expected nonces %d confirmed and pending, got %d and %d", i, first+3, confirmed, nm.peek(sender.addr))
+		}
+	}
+
+	// Another client keeps using the nonces, the manager gives up after maxNonceRetries
+	bor.Handle("eth_getTransactionCount", func(params []json.RawMessage) (interface{}, error) {
+		return hexutil.Uint64(0), nil
+	})
+	_, err = nm.sendTransaction(client, senders[1], func(nonce uint64) *types.Transaction {
+		return transfer(senders[1], 0)
+	})
+	if err == nil || !strings.Contains(err.Error(), "nonce too low") {
+		t.Errorf("expected nonce too low, got %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The execution-apis document describes the eth_* methods, the Bor one the bor_* methods
+// and the geth extensions served by Bor. Both use the layout of the openrpc.json
+// published by ethereum/execution-apis, which can replace the embedded one.
+//
+//go:embed schemas/*.json
+var embeddedSchemas embed.FS
+
+const (
+	executionAPIsSchemaFile = "schemas/execution-apis.json"
+	borSchemaFile           = "schemas/bor.json"
+	componentRefPrefix      = "#/components/schemas/"
+)
+
+type openRPCDocument struct {
+	Methods []struct {
+		Name   string `json:"name"`
+		Result *struct {
+			Name   string      `json:"name"`
+			Schema *jsonSchema `json:"schema"`
+		} `json:"result"`
+	} `json:"methods"`
+	Components struct {
+		Schemas map[string]*jsonSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+// jsonSchema holds the JSON Schema keywords used by the execution-apis specification.
+// additionalProperties is not enforced: Bor adds its own fields to several objects.
+type jsonSchema struct {
+	Ref        string                 `json:"$ref"`
+	Title      string                 `json:"title"`
+	Type       schemaTypes            `json:"type"`
+	Pattern    string                 `json:"pattern"`
+	Enum       []interface{}          `json:"enum"`
+	Required   []string               `json:"required"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Items      *jsonSchema            `json:"items"`
+	AllOf      []*jsonSchema          `json:"allOf"`
+	AnyOf      []*jsonSchema          `json:"anyOf"`
+	OneOf      []*jsonSchema          `json:"oneOf"`
+}
+
+// schemaTypes is the "type" keyword, either a single type or a list of types.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings: %w", err)
+	}
+	*t = list
+	return nil
+}
+
+// schemaValidator checks method results against the schemas of the loaded documents.
+type schemaValidator struct {
+	methods    map[string]*jsonSchema
+	components map[string]*jsonSchema
+	patterns   map[string]*regexp.Regexp
+}
+
+// loadSchemaValidator loads the embedded documents. When specPath is set, it is used
+// instead of the embedded execution-apis document.
+func loadSchemaValidator(specPath string) (*schemaValidator, error) {
+	v := &schemaValidator{
+		methods:    make(map[string]*jsonSchema),
+		components: make(map[string]*jsonSchema),
+		patterns:   make(map[string]*regexp.Regexp),
+	}
+
+	executionAPIs, err := embeddedSchemas.ReadFile(executionAPIsSchemaFile)
+	if specPath != "" {
+		executionAPIs, err = os.ReadFile(specPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := v.addDocument(executionAPIs); err != nil {
+		return nil, fmt.Errorf("invalid execution-apis specification: %w", err)
+	}
+
+	// Bor schemas are added last so that they can extend execution-apis components
+	bor, err := embeddedSchemas.ReadFile(borSchemaFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.addDocument(bor); err != nil {
+		return nil, fmt.Errorf("invalid Bor specification: %w", err)
+	}
+
+	for name, schema := range v.components {
+		if err := v.compile(schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	for name, schema := range v.methods {
+		if err := v.compile(schema); err != nil {
+			return nil, fmt.Errorf("result of %s: %w", name, err)
+		}
+	}
+	return v, nil
+}
+
+func (v *schemaValidator) addDocument(data []byte) error {
+	var document openRPCDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	for name, schema := range document.Components.Schemas {
+		v.components[name] = schema
+	}
+	for _, method := range document.Methods {
+		if method.Result != nil && method.Result.Schema != nil {
+			v.methods[method.Name] = method.Result.Schema
+		}
+	}
+	return nil
+}
+
+// compile checks that every reference of a schema resolves and compiles its patterns.
+func (v *schemaValidator) compile(schema *jsonSchema) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		if _, err := v.resolve(schema.Ref); err != nil {
+			return err
+		}
+	}
+	if schema.Pattern != "" && v.patterns[schema.Pattern] == nil {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", schema.Pattern, err)
+		}
+		v.patterns[schema.Pattern] = pattern
+	}
+	children := append(append(append([]*jsonSchema{schema.Items}, schema.AllOf...), schema.AnyOf...), schema.OneOf...)
+	for _, property := range schema.Properties {
+		children = append(children, property)
+	}
+	for _, child := range children {
+		if err := v.compile(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *schemaValidator) resolve(ref string) (*jsonSchema, error) {
+	name, ok := strings.CutPrefix(ref, componentRefPrefix)
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference %q", ref)
+	}
+	schema, ok := v.components[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+	return schema, nil
+}
+
+// validateResult checks the result of a method against its schema. Methods without a
+// schema, and every method when the validator is disabled, are accepted as is.
+func (v *schemaValidator) validateResult(method string, result json.RawMessage) error {
+	if v == nil {
+		return nil
+	}
+	schema, ok := v.methods[method]
+	if !ok {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("error unmarshalling JSON: %w", err)
+	}
+	if err := v.validate(schema, value, "result"); err != nil {
+		return fmt.Errorf("result does not match the %s schema: %w", method, err)
+	}
+	return nil
+}
+
+func (v *schemaValidator) validate(schema *jsonSchema, value interface{}, path string) error {
+	if schema.Ref != "" {
+		resolved, err := v.resolve(schema.Ref)
+		if err != nil {
+			return err
+		}
+		return v.validate(resolved, value, path)
+	}
+
+	if len(schema.Type) > 0 && !matchesSchemaType(schema.Type, value) {
+		return &schemaTypeError{path: path, expected: schema.Type, actual: jsonTypeOf(value)}
+	}
+	if s, ok := value.(string); ok && schema.Pattern != "" && !v.patterns[schema.Pattern].MatchString(s) {
+		return fmt.Errorf("%s: %q does not match %s", path, s, schema.Pattern)
+	}
+	if len(schema.Enum) > 0 && !containsValue(schema.Enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, schema.Enum)
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				return fmt.Errorf("%s: missing required field %s", path, name)
+			}
+		}
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if field, ok := value[name]; ok {
+				if err := v.validate(schema.Properties[name], field, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range value {
+				if err := v.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, sub := range schema.AllOf {
+		if err := v.validate(sub, value, path); err != nil {
+			return err
+		}
+	}
+	// oneOf is checked like anyOf: the specification uses it for alternatives that
+	// overlap, e.g. an empty list is both a list of hashes and a list of logs
+	for _, alternatives := range [][]*jsonSchema{schema.AnyOf, schema.OneOf} {
+		if len(alternatives) == 0 {
+			continue
+		}
+		if err := v.validateAlternatives(alternatives, value, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAlternatives checks that value matches at least one of the alternatives. The
+// error only describes the alternatives of the same type as value, if there are any.
+func (v *schemaValidator) validateAlternatives(alternatives []*jsonSchema, value interface{}, path string) error {
+	var sameType, otherType []string
+	var lastErr error
+	for _, alternative := range alternatives {
+		err := v.validate(alternative, value, path)
+		if err == nil {
+			return nil
+		}
+		description := fmt.Sprintf("%s (%v)", v.title(alternative), err)
+		var typeErr *schemaTypeError
+		if errors.As(err, &typeErr) && typeErr.path == path {
+			otherType = append(otherType, description)
+			continue
+		}
+		sameType = append(sameType, description)
+		lastErr = err
+	}
+	if len(sameType) == 1 {
+		return lastErr
+	}
+	if len(sameType) == 0 {
+		sameType = otherType
+	}
+	return fmt.Errorf("%s: matches none of the alternatives: %s", path, strings.Join(sameType, "; "))
+}
+
+// schemaTypeError is returned when a value does not have the type required by a schema.
+type schemaTypeError struct {
+	path     string
+	expected []string
+	actual   string
+}
+
+func (e *schemaTypeError) Error() string {
+	return fmt.Sprintf("%s: expected %s, got %s", e.path, strings.Join(e.expected, " or "), e.actual)
+}
+
+// title names a schema in error messages.
+func (v *schemaValidator) title(schema *jsonSchema) string {
+	if schema.Title != "" {
+		return schema.Title
+	}
+	if schema.Ref != "" {
+		return strings.TrimPrefix(schema.Ref, componentRefPrefix)
+	}
+	return strings.Join(schema.Type, " or ")
+}
+
+func matchesSchemaType(schemaTypes []string, value interface{}) bool {
+	actual := jsonTypeOf(value)
+	for _, schemaType := range schemaTypes {
+		if schemaType == actual || (schemaType == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if number, ok := value.(json.Number); ok {
+			if fmt.Sprint(candidate) == number.String() {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedSchemas(t *testing.T) {
+	if _, err := loadSchemaValidator(""); err != nil {
+		t.Fatalf("loadSchemaValidator: %v", err)
+	}
+
+	// Bor specific methods belong to bor.json, not to the execution-apis document
+	data, err := embeddedSchemas.ReadFile(executionAPIsSchemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document openRPCDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range document.Methods {
+		if !strings.HasPrefix(method.Name, "eth_") && !strings.HasPrefix(method.Name, "debug_") && !strings.HasPrefix(method.Name, "engine_") {
+			t.Errorf("%s is not an execution-apis method, it belongs to %s", method.Name, borSchemaFile)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// proofDatabase indexes the nodes of a Merkle-Patricia proof by their hash, as
+// trie.VerifyProof expects them.
+func proofDatabase(proof []string) (*memorydb.Database, error) {
+	db := memorydb.New()
+	for i, encoded := range proof {
+		node, err := hexutil.Decode(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof node %d: %w", i, err)
+		}
+		if err := db.Put(crypto.Keccak256(node), node); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// verifyAccountProof checks the account proof of an eth_getProof result against the
+// state root of the requested block, then checks the returned account fields against
+// the proven account. An account missing from the state must be returned empty.
+func verifyAccountProof(stateRoot common.Hash, result *accountResult) error {
+	db, err := proofDatabase(result.AccountProof)
+	if err != nil {
+		return fmt.Errorf("invalid accountProof: %w", err)
+	}
+	value, err := trie.VerifyProof(stateRoot, crypto.Keccak256(result.Address.Bytes()), db)
+	if err != nil {
+		return fmt.Errorf("accountProof of %s does not verify against state root %s: %w", result.Address, stateRoot, err)
+	}
+
+	account := types.StateAccount{
+		Root:     types.EmptyRootHash,
+		CodeHash: types.EmptyCodeHash.Bytes(),
+	}
+	balance := new(big.Int)
+	if value != nil {
+		if err := rlp.DecodeBytes(value, &account); err != nil {
+			return fmt.Errorf("invalid account %s in accountProof: %w", result.Address, err)
+		}
+		balance = account.Balance.ToBig()
+	}
+
+	if result.Balance == nil || result.Balance.ToInt().Cmp(balance) != 0 {
+		return fmt.Errorf("invalid balance: proven %s, returned %v", balance, result.Balance)
+	}
+	if uint64(result.Nonce) != account.Nonce {
+		return fmt.Errorf("invalid nonce: proven %d, returned %d", account.Nonce, result.Nonce)
+	}
+	if result.StorageHash != account.Root {
+		return fmt.Errorf("invalid storageHash: proven %s, returned %s", account.Root, result.StorageHash)
+	}
+	if !bytes.Equal(result.CodeHash.Bytes(), account.CodeHash) {
+		return fmt.Errorf("invalid codeHash: proven %x, returned %s", account.CodeHash, result.CodeHash)
+	}
+	return nil
+}
+
+// verifyStorageProof checks a storage proof of an eth_getProof result against the
+// storage root of its account, then checks the returned value against the proven one.
+func verifyStorageProof(storageHash common.Hash, result storageResult) (*big.Int, error) {
+	key, err := hexutil.Decode(result.Key)
+	if err != nil || len(key) > common.HashLength {
+		return nil, fmt.Errorf("invalid storage key %q", result.Key)
+	}
+	if result.Value == nil {
+		return nil, fmt.Errorf("missing value of storage key %s", result.Key)
+	}
+
+	value := new(big.Int)
+	// Nothing is stored under an empty storage trie, and its root node is not part of the proof
+	if storageHash != types.EmptyRootHash {
+		db, err := proofDatabase(result.Proof)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof of storage key %s: %w", result.Key, err)
+		}
+		slot := common.BytesToHash(key)
+		encoded, err := trie.VerifyProof(storageHash, crypto.Keccak256(slot.Bytes()), db)
+		if err != nil {
+			return nil, fmt.Errorf("proof of storage key %s does not verify against storage hash %s: %w", result.Key, storageHash, err)
+		}
+		if encoded != nil {
+			_, content, _, err := rlp.Split(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of storage key %s in proof: %w", result.Key, err)
+			}
+			value.SetBytes(content)
+		}
+	}
+
+	if result.Value.ToInt().Cmp(value) != 0 {
+		return nil, fmt.Errorf("invalid value of storage key %s: proven %s, returned %s", result.Key, value, result.Value.ToInt())
+	}
+	return value, nil
+}
+
+// mappingSlot returns the storage slot of mapping[key] for a mapping declared at slot
+// and keyed by string, as laid out by solidity.
+func mappingSlot(key string, slot uint64) common.Hash {
+	return crypto.Keccak256Hash([]byte(key), common.BigToHash(new(big.Int).SetUint64(slot)).Bytes())
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// proofNodes collects the nodes written by trie.Prove, as eth_getProof returns them.
+type proofNodes []string
+
+func (p *proofNodes) Put(key []byte, value []byte) error {
+	*p = append(*p, hexutil.Encode(value))
+	return nil
+}
+
+func (p *proofNodes) Delete(key []byte) error {
+	return nil
+}
+
+func prove(t *testing.T, tr *trie.Trie, key []byte) []string {
+	t.Helper()
+	var proof proofNodes
+	if err := tr.Prove(crypto.Keccak256(key), &proof); err != nil {
+		t.Fatalf("Prove: %v", err)
+	}
+	return proof
+}
+
+func update(t *testing.T, tr *trie.Trie, key []byte, value interface{}) {
+	t.Helper()
+	encoded, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Update(crypto.Keccak256(key), encoded); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyProofs(t *testing.T) {
+	storage := trie.NewEmpty(nil)
+	for slot, value := range map[int64]int64{1: 42, 3: 7} {
+		update(t, storage, common.BigToHash(big.NewInt(slot)).Bytes(), big.NewInt(value).Bytes())
+	}
+	storageHash := storage.Hash()
+
+	addr := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	missing := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	codeHash := crypto.Keccak256Hash([]byte{0x60, 0x00})
+	state := trie.NewEmpty(nil)
+	update(t, state, addr.Bytes(), []interface{}{uint64(5), big.NewInt(1000), storageHash, codeHash})
+	update(t, state, other.Bytes(), []interface{}{uint64(1), big.NewInt(1), types.EmptyRootHash, types.EmptyCodeHash})
+	stateRoot := state.Hash()
+
+	account := func(mutate func(*accountResult)) *accountResult {
+		result := &accountResult{
+			Address:      addr,
+			AccountProof: prove(t, state, addr.Bytes()),
+			Balance:      (*hexutil.Big)(big.NewInt(1000)),
+			CodeHash:     codeHash,
+			Nonce:        5,
+			StorageHash:  storageHash,
+		}
+		if mutate != nil {
+			mutate(result)
+		}
+		return result
+	}
+	emptyAccount := &accountResult{
+		Address:      missing,
+		AccountProof: prove(t, state, missing.Bytes()),
+		Balance:      (*hexutil.Big)(big.NewInt(0)),
+		CodeHash:     types.EmptyCodeHash,
+		StorageHash:  types.EmptyRootHash,
+	}
+
+	for _, tt := range []struct {
+		name      string
+		stateRoot common.Hash
+		result    *accountResult
+		err       string
+	}{
+		{"valid", stateRoot, account(nil), ""},
+		{"missing account returned empty", stateRoot, emptyAccount, ""},
+		{"missing account returned with a balance", stateRoot, &accountResult{Address: missing, AccountProof: emptyAccount.AccountProof, Balance: (*hexutil.Big)(big.NewInt(1)), CodeHash: types.EmptyCodeHash, StorageHash: types.EmptyRootHash}, "invalid balance: proven 0, returned 0x1"},
+		{"balance", stateRoot, account(func(r *accountResult) { r.Balance = (*hexutil.Big)(big.NewInt(999)) }), "invalid balance"},
+		{"no balance", stateRoot, account(func(r *accountResult) { r.Balance = nil }), "invalid balance"},
+		{"nonce", stateRoot, account(func(r *accountResult) { r.Nonce = 6 }), "invalid nonce: proven 5, returned 6"},
+		{"storage hash", stateRoot, account(func(r *accountResult) { r.StorageHash = types.EmptyRootHash }), "invalid storageHash"},
+		{"code hash", stateRoot, account(func(r *accountResult) { r.CodeHash = types.EmptyCodeHash }), "invalid codeHash"},
+		{"other state root", common.HexToHash("0x01"), account(nil), "does not verify against state root"},
+		{"proof of another account", stateRoot, account(func(r *accountResult) { r.AccountProof = prove(t, state, other.Bytes())[1:] }), "does not verify"},
+		{"malformed node", stateRoot, account(func(r *accountResult) { r.AccountProof = []string{"0xzz"} }), "invalid accountProof"},
+	} {
+		err := verifyAccountProof(tt.stateRoot, tt.result)
+		if tt.err == "" && err != nil {
+			t.Errorf("account %s: %v", tt.name, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("account %s: expected error %q, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	slot := func(n int64, value int64) storageResult {
+		key := common.BigToHash(big.NewInt(n))
+		return storageResult{
+			Key:   hexutil.Encode(big.NewInt(n).Bytes()),
+			Value: (*hexutil.Big)(big.NewInt(value)),
+			Proof: prove(t, storage, key.Bytes()),
+		}
+	}
+	for _, tt := range []struct {
+		name        string
+		storageHash common.Hash
+		result      storageResult
+		expected    int64
+		err         string
+	}{
+		{"stored value", storageHash, slot(1, 42), 42, ""},
+		{"unset slot", storageHash, slot(2, 0), 0, ""},
+		{"value", storageHash, slot(3, 8), 0, "invalid value of storage key 0x03: proven 7, returned 8"},
+		{"empty storage", types.EmptyRootHash, storageResult{Key: "0x01", Value: (*hexutil.Big)(big.NewInt(0))}, 0, ""},
+		{"value in empty storage", types.EmptyRootHash, storageResult{Key: "0x01", Value: (*hexutil.Big)(big.NewInt(1))}, 0, "proven 0, returned 1"},
+		{"other storage hash", common.HexToHash("0x01"), slot(1, 42), 0, "does not verify against storage hash"},
+		{"no value", storageHash, storageResult{Key: "0x01"}, 0, "missing value of storage key 0x01"},
+		{"key too long", storageHash, storageResult{Key: hexutil.Encode(make([]byte, 33))}, 0, "invalid storage key"},
+	} {
+		value, err := verifyStorageProof(tt.storageHash, tt.result)
+		if tt.err == "" && err != nil {
+			t.Errorf("storage %s: %v", tt.name, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("storage %s: expected error %q, got %v", tt.name, tt.err, err)
+		} else if tt.err == "" && value.Cmp(big.NewInt(tt.expected)) != 0 {
+			t.Errorf("storage %s: expected value %d, got %s", tt.name, tt.expected, value)
+		}
+	}
+}
+
+func TestMappingSlot(t *testing.T) {
+	// keccak256(abi.encodePacked("key", uint256(2)))
+	expected := crypto.Keccak256Hash(append([]byte("key"), common.LeftPadBytes([]byte{2}, 32)...))
+	if slot := mappingSlot("key", 2); slot != expected {
+		t.Errorf("expected slot %s, got %s", expected, slot)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"math/rand"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// runRandomness is the random state of a run. Request IDs are allocated sequentially so
+// they never collide, and every other random value is drawn from a source seeded with
+// --seed, so that a failing run can be replayed with identical payloads.
+type runRandomness struct {
+	mu     sync.Mutex
+	seed   int64
+	source *rand.Rand
+	lastID int
+}
+
+// randomness is replaced in main once --seed is parsed.
+var randomness = newRunRandomness(0)
+
+func newRunRandomness(seed int64) *runRandomness {
+	return &runRandomness{seed: seed, source: rand.New(rand.NewSource(seed))}
+}
+
+// nextRequestID returns an ID no other request of the run has.
+func (r *runRandomness) nextRequestID() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastID++
+	return r.lastID
+}
+
+// generateKey derives a private key from the seeded source, unlike crypto.GenerateKey
+// which reads from the system randomness.
+func (r *runRandomness) generateKey() (*ecdsa.PrivateKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		seed := make([]byte, 32)
+		r.source.Read(seed)
+		// Out of range scalars are rejected, draw again in the unlikely case of one
+		if key, err := crypto.ToECDSA(seed); err == nil {
+			return key, nil
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	junitReportFileName = "rpc-tests-junit.xml"
+	jsonReportFileName  = "rpc-tests-report.json"
+	// coverageReportFileName is written by --coverage
+	coverageReportFileName = "rpc-tests-coverage.json"
+)
+
+// jsonReport is the machine-readable report written to jsonReportFileName.
+type jsonReport struct {
+	RPCURL          string               `json:"rpcUrl"`
+	ClientVersion   string               `json:"clientVersion,omitempty"`
+	Seed            int64                `json:"seed"`
+	StartedAt       time.Time            `json:"startedAt"`
+	DurationMs      float64              `json:"durationMs"`
+	Total           int                  `json:"total"`
+	Passed          int                  `json:"passed"`
+	Failed          int                  `json:"failed"`
+	Skipped         int                  `json:"skipped"`
+	Errored         int                  `json:"errored"`
+	TestCases       []jsonTestResult     `json:"testCases"`
+	Batches         []jsonBatch          `json:"batches"`
+	Latencies       []jsonLatency        `json:"latencies,omitempty"`
+	Inclusions      []jsonInclusion      `json:"inclusions,omitempty"`
+	StateSyncEvents []jsonStateSyncEvent `json:"stateSyncEvents,omitempty"`
+}
+
+type jsonTestResult struct {
+	Key        string      `json:"key"`
+	Method     string      `json:"method,omitempty"`
+	Batch      int         `json:"batch"`
+	Status     TestStatus  `json:"status"`
+	DurationMs float64     `json:"durationMs"`
+	Error      string      `json:"error,omitempty"`
+	Request    *Request    `json:"request,omitempty"`
+	Response   *Response   `json:"response,omitempty"`
+	Diffs      []FieldDiff `json:"diffs,omitempty"`
+}
+
+type jsonBatch struct {
+	Batch          int     `json:"batch"`
+	TestCases      int     `json:"testCases"`
+	DurationMs     float64 `json:"durationMs"`
+	CallDurationMs float64 `json:"callDurationMs"`
+}
+
+type jsonLatency struct {
+	Method  string  `json:"method"`
+	Samples int     `json:"samples"`
+	Errors  int     `json:"errors"`
+	P50Ms   float64 `json:"p50Ms"`
+	P95Ms   float64 `json:"p95Ms"`
+	P99Ms   float64 `json:"p99Ms"`
+	MaxMs   float64 `json:"maxMs"`
+}
+
+type jsonInclusion struct {
+	TxHash      string  `json:"txHash"`
+	BlockNumber string  `json:"blockNumber"`
+	DurationMs  float64 `json:"durationMs"`
+}
+
+type jsonStateSyncEvent struct {
+	StateID     uint64 `json:"stateId"`
+	BlockNumber uint64 `json:"blockNumber"`
+	TxHash      string `json:"txHash"`
+	Success     bool   `json:"success"`
+	Contract    string `json:"contract,omitempty"`
+	L1TxHash    string `json:"l1TxHash,omitempty"`
+	Verified    bool   `json:"verified"`
+	Error       string `json:"error,omitempty"`
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeReports writes a JUnit XML and a JSON report of the run into dir.
+// Request and response payloads are only included when --log-req-res is set.
+func writeReports(dir string, summary RunSummary) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating report directory: %w", err)
+	}
+
+	report := buildJSONReport(summary)
+
+	jsonBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling JSON report: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, jsonReportFileName), jsonBytes, 0644); err != nil {
+		return fmt.Errorf("error writing JSON report: %w", err)
+	}
+
+	xmlBytes, err := xml.MarshalIndent(buildJUnitReport(report), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling JUnit report: %w", err)
+	}
+	xmlBytes = append([]byte(xml.Header), xmlBytes...)
+	if err := os.WriteFile(filepath.Join(dir, junitReportFileName), xmlBytes, 0644); err != nil {
+		return fmt.Errorf("error writing JUnit report: %w", err)
+	}
+
+	return nil
+}
+
+func buildJSONReport(summary RunSummary) jsonReport {
+	report := jsonReport{
+		RPCURL:        *rpcURL,
+		ClientVersion: summary.ClientVersion,
+		Seed:          summary.Seed,
+		StartedAt:     summary.StartedAt.UTC(),
+		DurationMs:    durationToMs(summary.Duration),
+		Total:         len(summary.Results),
+		TestCases:     make([]jsonTestResult, 0, len(summary.Results)),
+		Batches:       make([]jsonBatch, 0, len(summary.Batches)),
+	}
+
+	for _, batch := range summary.Batches {
+		report.Batches = append(report.Batches, jsonBatch{
+			Batch:          batch.Index,
+			TestCases:      batch.Size,
+			DurationMs:     durationToMs(batch.Duration),
+			CallDurationMs: durationToMs(batch.CallDuration),
+		})
+	}
+
+	for _, latency := range summary.Latencies {
+		report.Latencies = append(report.Latencies, jsonLatency{
+			Method:  latency.Method,
+			Samples: latency.Samples,
+			Errors:  latency.Errors,
+			P50Ms:   durationToMs(latency.P50),
+			P95Ms:   durationToMs(latency.P95),
+			P99Ms:   durationToMs(latency.P99),
+			MaxMs:   durationToMs(latency.Max),
+		})
+	}
+
+	for _, inclusion := range summary.Inclusions {
+		report.Inclusions = append(report.Inclusions, jsonInclusion{
+			TxHash:      inclusion.TxHash.Hex(),
+			BlockNumber: inclusion.BlockNumber.String(),
+			DurationMs:  durationToMs(inclusion.Duration),
+		})
+	}
+
+	for _, check := range summary.StateSyncEvents {
+		event := jsonStateSyncEvent{
+			StateID:     check.StateID,
+			BlockNumber: check.BlockNumber,
+			TxHash:      check.TxHash.Hex(),
+			Success:     check.Success,
+			Verified:    check.Verified,
+		}
+		if (check.Contract != common.Address{}) {
+			event.Contract = check.Contract.Hex()
+			event.L1TxHash = check.L1TxHash.Hex()
+		}
+		if check.Err != nil {
+			event.Error = check.Err.Error()
+		}
+		report.StateSyncEvents = append(report.StateSyncEvents, event)
+	}
+
+	for _, result := range summary.Results {
+		testResult := jsonTestResult{
+			Key:        result.Key,
+			Batch:      result.Batch,
+			Status:     result.Status,
+			DurationMs: durationToMs(result.Duration),
+			Diffs:      result.Diffs,
+		}
+		if result.Req != nil {
+			testResult.Method = result.Req.Method
+		}
+		if result.Err != nil {
+			testResult.Error = result.Err.Error()
+		}
+		if *logReqRes {
+			testResult.Request = result.Req
+			testResult.Response = result.Res
+		}
+
+		switch result.Status {
+		case TestStatusPassed:
+			report.Passed++
+		case TestStatusFailed:
+			report.Failed++
+		case TestStatusSkipped:
+			report.Skipped++
+		case TestStatusErrored:
+			report.Errored++
+		}
+		report.TestCases = append(report.TestCases, testResult)
+	}
+
+	return report
+}
+
+// buildJUnitReport converts the JSON report into JUnit XML, with one test suite per batch.
+func buildJUnitReport(report jsonReport) junitTestSuites {
+	suites := junitTestSuites{
+		Name:     "rpc_tests",
+		Tests:    report.Total,
+		Failures: report.Failed,
+		Errors:   report.Errored,
+		Skipped:  report.Skipped,
+		Time:     msToSeconds(report.DurationMs),
+	}
+
+	var properties []junitProperty
+	properties = append(properties, junitProperty{Name: "rpcUrl", Value: report.RPCURL})
+	properties = append(properties, junitProperty{Name: "seed", Value: strconv.FormatInt(report.Seed, 10)})
+	if report.ClientVersion != "" {
+		properties = append(properties, junitProperty{Name: "clientVersion", Value: report.ClientVersion})
+	}
+
+	batchDurationsMs := make(map[int]float64)
+	for _, batch := range report.Batches {
+		batchDurationsMs[batch.Batch] = batch.DurationMs
+	}
+
+	suiteIndex := make(map[int]int)
+	for _, testResult := range report.TestCases {
+		i, ok := suiteIndex[testResult.Batch]
+		if !ok {
+			i = len(suites.Suites)
+			suiteIndex[testResult.Batch] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{
+				Name:       fmt.Sprintf("batch %d", testResult.Batch),
+				Time:       msToSeconds(batchDurationsMs[testResult.Batch]),
+				Timestamp:  report.StartedAt.Format("2006-01-02T15:04:05"),
+				Properties: properties,
+			})
+		}
+		suite := &suites.Suites[i]
+
+		testCase := junitTestCase{
+			Name:      testResult.Key,
+			ClassName: "rpc_tests",
+			Time:      msToSeconds(testResult.DurationMs),
+		}
+		if testResult.Method != "" {
+			testCase.ClassName = "rpc_tests." + testResult.Method
+		}
+		if *logReqRes {
+			request, _ := json.Marshal(testResult.Request)
+			response, _ := json.Marshal(testResult.Response)
+			testCase.SystemOut = fmt.Sprintf("Request: %s\nResponse: %s\n", request, response)
+		}
+
+		switch testResult.Status {
+		case TestStatusFailed:
+			body := testResult.Error
+			for _, diff := range testResult.Diffs {
+				primary, _ := json.Marshal(diff.Primary)
+				baseline, _ := json.Marshal(diff.Baseline)
+				body += fmt.Sprintf("\n%s: %s (baseline: %s)", diff.Path, primary, baseline)
+			}
+			testCase.Failure = &junitMessage{Message: testResult.Error, Body: body}
+			suite.Failures++
+		case TestStatusErrored:
+			testCase.Error = &junitMessage{Message: testResult.Error, Body: testResult.Error}
+			suite.Errors++
+		case TestStatusSkipped:
+			testCase.Skipped = &junitMessage{Message: testResult.Error}
+			suite.Skipped++
+		}
+
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	return suites
+}
+
+// fetchClientVersion returns the node's web3_clientVersion so reports can be
+// compared across Bor releases. Errors are ignored since it is informational only.
+func fetchClientVersion(client RPCClient) string {
+	responses, err := client.Call([]Request{*NewRequest("web3_clientVersion", []interface{}{})})
+	if err != nil || len(responses) != 1 || responses[0].Error != nil {
+		return ""
+	}
+	version, err := parseResponse[string](responses[0].Result)
+	if err != nil {
+		return ""
+	}
+	return *version
+}
+
+func durationToMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func msToSeconds(ms float64) string {
+	return fmt.Sprintf("%.3f", ms/1000)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReportsCountErroredTestCases(t *testing.T) {
+	report := buildJSONReport(RunSummary{Results: []TestResult{
+		{Key: "passed", Status: TestStatusPassed},
+		{Key: "failed", Status: TestStatusFailed, Err: errors.New("invalid result")},
+		{Key: "errored", Status: TestStatusErrored, Err: errors.New("received 2 responses for request id 7")},
+	}})
+	if report.Passed != 1 || report.Failed != 1 || report.Errored != 1 {
+		t.Fatalf("expected 1 passed, failed and errored test case, got %+v", report)
+	}
+
+	junit := buildJUnitReport(report)
+	if junit.Errors != 1 || junit.Failures != 1 || len(junit.Suites) != 1 || junit.Suites[0].Errors != 1 {
+		t.Fatalf("expected 1 error and 1 failure, got %+v", junit)
+	}
+	errored := junit.Suites[0].TestCases[2]
+	if errored.Error == nil || errored.Error.Message != "received 2 responses for request id 7" || errored.Failure != nil {
+		t.Errorf("expected an <error> element, got %+v", errored)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// scheduleTestCases groups test cases into batches based on the ResponseMap fields
+// they declare in Requires and Produces. A test case is placed in the batch right
+// after the last batch containing a producer of any field it requires, so test
+// cases without dependencies all run in the first batch. The order of test cases
+// inside a batch follows their order in the given slice.
+func scheduleTestCases(testCases []TestCase) ([]BatchTestCase, error) {
+	producers := make(map[string][]int)
+	for i, testCase := range testCases {
+		for _, field := range testCase.Produces {
+			producers[field] = append(producers[field], i)
+		}
+	}
+
+	// Build the dependency graph: dependents[i] holds the test cases that need
+	// a field produced by test case i
+	dependents := make([][]int, len(testCases))
+	inDegree := make([]int, len(testCases))
+	for i, testCase := range testCases {
+		seen := make(map[int]bool)
+		for _, field := range testCase.Requires {
+			fieldProducers, ok := producers[field]
+			if !ok {
+				return nil, fmt.Errorf("test case %q requires %q but no selected test case produces it", testCase.Key, field)
+			}
+			for _, producer := range fieldProducers {
+				if producer == i {
+					return nil, fmt.Errorf("test case %q requires %q which it produces itself", testCase.Key, field)
+				}
+				if seen[producer] {
+					continue
+				}
+				seen[producer] = true
+				dependents[producer] = append(dependents[producer], i)
+				inDegree[i]++
+			}
+		}
+	}
+
+	// Kahn's algorithm, one level at a time
+	var current []int
+	for i := range testCases {
+		if inDegree[i] == 0 {
+			current = append(current, i)
+		}
+	}
+
+	var batches []BatchTestCase
+	scheduled := 0
+	for len(current) > 0 {
+		sort.Ints(current)
+		batch := make(BatchTestCase, 0, len(current))
+		var next []int
+		for _, i := range current {
+			batch = append(batch, testCases[i])
+			scheduled++
+			for _, dependent := range dependents[i] {
+				inDegree[dependent]--
+				if inDegree[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		batches = append(batches, batch)
+		current = next
+	}
+
+	if scheduled != len(testCases) {
+		var cycle []string
+		for i, testCase := range testCases {
+			if inDegree[i] > 0 {
+				cycle = append(cycle, testCase.Key)
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle involving test cases: %s", strings.Join(cycle, ", "))
+	}
+
+	return batches, nil
+}
+
+// unavailableDependency returns the key of the test case responsible for a field
+// required by testCase not being available, if any.
+func unavailableDependency(testCase TestCase, unavailableFields map[string]string) (string, bool) {
+	for _, field := range testCase.Requires {
+		if cause, ok := unavailableFields[field]; ok {
+			return cause, true
+		}
+	}
+	return "", false
+}
+
+// markProducedFieldsUnavailable records that the fields produced by testCase will
+// never be set because of the test case identified by cause.
+func markProducedFieldsUnavailable(testCase TestCase, cause string, unavailableFields map[string]string) {
+	for _, field := range testCase.Produces {
+		if _, ok := unavailableFields[field]; !ok {
+			unavailableFields[field] = cause
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// batchIndexes returns the batch every scheduled test case is in, by key.
+func batchIndexes(batches []BatchTestCase) map[string]int {
+	indexes := make(map[string]int)
+	for i, batch := range batches {
+		for _, testCase := range batch {
+			indexes[testCase.Key] = i
+		}
+	}
+	return indexes
+}
+
+func TestScheduleBlockFilterAfterTransaction(t *testing.T) {
+	batches, err := scheduleTestCases(testCases)
+	if err != nil {
+		t.Fatalf("scheduleTestCases: %v", err)
+	}
+	indexes := batchIndexes(batches)
+	receipt := indexes["Create Transaction Scenario: eth_getTransactionReceipt"]
+	filter := indexes["Create Transaction Scenario: eth_newBlockFilter"]
+	changes := indexes["Create Transaction Scenario: eth_getFilterChanges (from eth_newBlockFilter)"]
+	if filter <= receipt {
+		t.Errorf("eth_newBlockFilter scheduled in batch %d, not after the transaction receipt in batch %d", filter, receipt)
+	}
+	if changes <= filter {
+		t.Errorf("eth_getFilterChanges scheduled in batch %d, not after eth_newBlockFilter in batch %d", changes, filter)
+	}
+}
+
+func TestScheduleTestCases(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		testCases []TestCase
+		expected  [][]string
+		err       string
+	}{
+		{
+			name: "independent test cases share the first batch in order",
+			testCases: []TestCase{
+				{Key: "b"},
+				{Key: "a"},
+			},
+			expected: [][]string{{"b", "a"}},
+		},
+		{
+			name: "a test case runs after every producer of what it requires",
+			testCases: []TestCase{
+				{Key: "uses x and y", Requires: []string{"x", "y"}},
+				{Key: "produces y", Requires: []string{"x"}, Produces: []string{"y"}},
+				{Key: "produces x", Produces: []string{"x"}},
+				{Key: "also produces x", Produces: []string{"x"}},
+			},
+			expected: [][]string{{"produces x", "also produces x"}, {"produces y"}, {"uses x and y"}},
+		},
+		{
+			name: "missing producer",
+			testCases: []TestCase{
+				{Key: "uses x", Requires: []string{"x"}},
+			},
+			err: `test case "uses x" requires "x" but no selected test case produces it`,
+		},
+		{
+			name: "self dependency",
+			testCases: []TestCase{
+				{Key: "x", Requires: []string{"x"}, Produces: []string{"x"}},
+			},
+			err: `test case "x" requires "x" which it produces itself`,
+		},
+		{
+			name: "cycle",
+			testCases: []TestCase{
+				{Key: "free"},
+				{Key: "a", Requires: []string{"b"}, Produces: []string{"a"}},
+				{Key: "b", Requires: []string{"a"}, Produces: []string{"b"}},
+				{Key: "after the cycle", Requires: []string{"a"}},
+			},
+			err: "dependency cycle involving test cases: a, b, after the cycle",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, err := scheduleTestCases(tt.testCases)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scheduleTestCases: %v", err)
+			}
+			keys := make([][]string, 0, len(batches))
+			for _, batch := range batches {
+				keys = append(keys, testCaseKeys(batch))
+			}
+			if !slices.EqualFunc(keys, tt.expected, slices.Equal) {
+				t.Errorf("expected batches %v, got %v", tt.expected, keys)
+			}
+		})
+	}
+}
+
+func TestSkipPropagation(t *testing.T) {
+	failed := TestCase{Key: "failed", Produces: []string{"x"}}
+	dependent := TestCase{Key: "dependent", Requires: []string{"x"}, Produces: []string{"y", "z"}}
+	transitive := TestCase{Key: "transitive", Requires: []string{"w", "y"}}
+	unrelated := TestCase{Key: "unrelated", Requires: []string{"w"}}
+
+	unavailableFields := make(map[string]string)
+	markProducedFieldsUnavailable(failed, failed.Key, unavailableFields)
+	for _, testCase := range []TestCase{dependent, transitive} {
+		cause, ok := unavailableDependency(testCase, unavailableFields)
+		if !ok || cause != failed.Key {
+			t.Errorf("%s: expected to be skipped because of %q, got %q (%t)", testCase.Key, failed.Key, cause, ok)
+		}
+		markProducedFieldsUnavailable(testCase, cause, unavailableFields)
+	}
+	if cause, ok := unavailableDependency(unrelated, unavailableFields); ok {
+		t.Errorf("unrelated: expected to run, skipped because of %q", cause)
+	}
+
+	// The first test case failing to produce a field stays its cause
+	markProducedFieldsUnavailable(TestCase{Key: "later", Produces: []string{"z"}}, "later", unavailableFields)
+	if cause := unavailableFields["z"]; cause != failed.Key {
+		t.Errorf("expected z unavailable because of %q, got %q", failed.Key, cause)
+	}
+	if cause := unavailableFields["y"]; cause != failed.Key {
+		t.Errorf("expected y unavailable because of %q, got %q", failed.Key, cause)
+	}
+}
@@ -0,0 +1,31 @@
+#!/bin/bash
+set -euo pipefail
+
+# Vendors the openrpc.json published with an ethereum/execution-apis release as the
+# embedded execution-apis.json, and records the release in execution-apis.version.
+# Bor specific schemas belong to bor.json, which is left untouched.
+
+if [ $# -ne 1 ]; then
+  echo "Usage: $0 <execution-apis release tag>"
+  exit 1
+fi
+RELEASE="$1"
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+TMP_FILE="$(mktemp)"
+trap 'rm -f "$TMP_FILE"' EXIT
+
+echo "Downloading openrpc.json of execution-apis ${RELEASE}..."
+curl -fsSL -o "$TMP_FILE" "https://github.com/ethereum/execution-apis/releases/download/${RELEASE}/openrpc.json"
+
+if ! jq -e '.methods | length > 0' "$TMP_FILE" > /dev/null; then
+  echo "The openrpc.json of ${RELEASE} has no methods"
+  exit 1
+fi
+
+jq . "$TMP_FILE" > "$SCRIPT_DIR/execution-apis.json"
+echo "$RELEASE" > "$SCRIPT_DIR/execution-apis.version"
+
+# The embedded documents must still load
+(cd "$SCRIPT_DIR/.." && go test -run TestEmbeddedSchemas .)
+echo "execution-apis.json updated to ${RELEASE}"
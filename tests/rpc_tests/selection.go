@@ -0,0 +1,171 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// scenarioTags maps the scenario prefix of a test case key to the tag of its group.
+var scenarioTags = map[string]string{
+	"Create Transaction Scenario": "create-transaction",
+	"StateSyncTx Scenario":        "state-sync",
+	"Subscription Scenario":       "subscription",
+	"DynamicFeeTx Scenario":       "dynamic-fee",
+	"AccessListTx Scenario":       "access-list",
+	"Declarative Scenario":        "declarative",
+	"Negative Scenario":           negativeTag,
+	"TxPool Scenario":             "txpool",
+	"Concurrency Scenario":        "concurrency",
+}
+
+var methodInKeyRegex = regexp.MustCompile(`\b((eth|bor|net|web3|txpool|debug)_[A-Za-z]+)`)
+
+// testCaseTags returns the explicit tags of a test case together with the derived ones:
+// the namespace of the called method (e.g. "bor"), the scenario group (e.g.
+// "state-sync"), "write" for test cases sending a transaction and "negative" for test
+// cases expecting an error. The called method is the declared one, or else the first
+// one the key names.
+func testCaseTags(testCase TestCase) []string {
+	tags := make(map[string]bool)
+	for _, tag := range testCase.Tags {
+		tags[tag] = true
+	}
+	if testCase.ExpectError != nil {
+		tags[negativeTag] = true
+	}
+	if method := testCaseMethod(testCase); method != "" {
+		if namespace, _, ok := strings.Cut(method, "_"); ok {
+			tags[namespace] = true
+		}
+		if isWriteMethod(method) {
+			tags["write"] = true
+		}
+	}
+	if scenario, _, ok := strings.Cut(testCase.Key, ":"); ok {
+		if tag, ok := scenarioTags[scenario]; ok {
+			tags[tag] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(tags))
+	for tag := range tags {
+		sorted = append(sorted, tag)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// testSelection holds the --run, --skip, --tags, --skip-tags and --read-only flags.
+type testSelection struct {
+	run      *regexp.Regexp
+	skip     *regexp.Regexp
+	tags     map[string]bool
+	skipTags map[string]bool
+	readOnly bool
+}
+
+func parseTags(spec string) map[string]bool {
+	tags := make(map[string]bool)
+	for _, tag := range strings.Split(spec, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags[tag] = true
+		}
+	}
+	return tags
+}
+
+// excludes reports whether a test case must not run, whatever depends on it.
+func (s testSelection) excludes(testCase TestCase) bool {
+	if s.skip != nil && s.skip.MatchString(testCase.Key) {
+		return true
+	}
+	for _, tag := range testCaseTags(testCase) {
+		if s.skipTags[tag] || (s.readOnly && tag == "write") {
+			return true
+		}
+	}
+	return false
+}
+
+// wants reports whether a test case was explicitly asked for.
+func (s testSelection) wants(testCase TestCase) bool {
+	if s.run != nil && !s.run.MatchString(testCase.Key) {
+		return false
+	}
+	if len(s.tags) == 0 {
+		return true
+	}
+	for _, tag := range testCaseTags(testCase) {
+		if s.tags[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTestCases returns, in their original order, the wanted test cases together with
+// the test cases producing what they require. Test cases requiring a field that only
+// excluded test cases produce are dropped and returned as unsatisfied.
+func selectTestCases(testCases []TestCase, selection testSelection) (selected []TestCase, unsatisfied []TestCase) {
+	producers := make(map[string][]int)
+	for i, testCase := range testCases {
+		if selection.excludes(testCase) {
+			continue
+		}
+		for _, field := range testCase.Produces {
+			producers[field] = append(producers[field], i)
+		}
+	}
+
+	// Pull in the producers of everything the wanted test cases require
+	included := make(map[int]bool)
+	var queue []int
+	for i, testCase := range testCases {
+		if !selection.excludes(testCase) && selection.wants(testCase) {
+			included[i] = true
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, field := range testCases[i].Requires {
+			for _, producer := range producers[field] {
+				if !included[producer] {
+					included[producer] = true
+					queue = append(queue, producer)
+				}
+			}
+		}
+	}
+
+	// Drop test cases whose requirements are no longer produced, until nothing changes
+	for changed := true; changed; {
+		changed = false
+		produced := make(map[string]bool)
+		for i := range included {
+			for _, field := range testCases[i].Produces {
+				produced[field] = true
+			}
+		}
+		for i := range included {
+			for _, field := range testCases[i].Requires {
+				if !produced[field] {
+					delete(included, i)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for i, testCase := range testCases {
+		if included[i] {
+			selected = append(selected, testCase)
+		} else if !selection.excludes(testCase) && selection.wants(testCase) {
+			unsatisfied = append(unsatisfied, testCase)
+		}
+	}
+	return selected, unsatisfied
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTestCaseTags(t *testing.T) {
+	for _, tt := range []struct {
+		testCase TestCase
+		expected []string
+	}{
+		{TestCase{Key: "bor_getAuthor"}, []string{"bor"}},
+		{TestCase{Key: "TxPool Scenario: eth_sendRawTransaction (nonce gap)"}, []string{"eth", "txpool", "write"}},
+		{TestCase{Key: "Negative Scenario: eth_call", ExpectError: &ExpectedError{Code: errCodeInvalidParams}}, []string{"eth", negativeTag}},
+		// The declared method wins over the key, which may name none or another one
+		{TestCase{Key: "fund the faucet", Method: "eth_sendRawTransaction", Tags: []string{"declarative"}}, []string{"declarative", "eth", "write"}},
+		{TestCase{Key: "custom check"}, []string{}},
+	} {
+		if tags := testCaseTags(tt.testCase); !slices.Equal(tags, tt.expected) {
+			t.Errorf("%q: expected tags %v, got %v", tt.testCase.Key, tt.expected, tags)
+		}
+	}
+}
+
+func TestReadOnlySelectionSkipsDeclaredWriteMethods(t *testing.T) {
+	testCases := []TestCase{
+		{Key: "send it", Method: "eth_sendRawTransaction", Produces: []string{"sent"}},
+		{Key: "check it", Method: "eth_getTransactionByHash", Requires: []string{"sent"}},
+		{Key: "read it", Method: "eth_blockNumber"},
+	}
+	selected, unsatisfied := selectTestCases(testCases, testSelection{readOnly: true})
+	if len(selected) != 1 || selected[0].Key != "read it" {
+		t.Errorf("expected only %q selected, got %v", "read it", testCaseKeys(selected))
+	}
+	if len(unsatisfied) != 1 || unsatisfied[0].Key != "check it" {
+		t.Errorf("expected only %q unsatisfied, got %v", "check it", testCaseKeys(unsatisfied))
+	}
+}
+
+func testCaseKeys(testCases []TestCase) []string {
+	keys := make([]string, 0, len(testCases))
+	for _, testCase := range testCases {
+		keys = append(keys, testCase.Key)
+	}
+	return keys
+}
+
+func TestReadOnlySelectionSkipsConcurrentSenders(t *testing.T) {
+	testCases := slices.Concat(testCases, concurrencyTestCases(2))
+	for _, selection := range []testSelection{
+		{readOnly: true},
+		{skipTags: map[string]bool{"write": true}},
+	} {
+		selected, _ := selectTestCases(testCases, selection)
+		for _, testCase := range selected {
+			if slices.Contains(testCaseTags(testCase), "write") {
+				t.Errorf("%+v: selected %q which sends transactions", selection, testCase.Key)
+			}
+			if slices.Contains(testCase.Requires, "concurrentTxs") || slices.Contains(testCase.Produces, "concurrentTxs") {
+				t.Errorf("%+v: selected %q of the concurrent senders", selection, testCase.Key)
+			}
+		}
+	}
+}
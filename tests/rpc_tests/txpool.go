@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// txPoolPriceBump is the minimum gas price bump, in percent, Bor requires to replace
+	// a pooled transaction (the txpool.pricebump default).
+	txPoolPriceBump = 10
+	// txPoolFunding is how many transfers at twice the gas price the sender of the
+	// txpool scenario is funded for.
+	txPoolFunding = 10
+	// txPoolQueuedNonce is the nonce of the transaction queued by the txpool scenario,
+	// leaving nonce 0 as the gap.
+	txPoolQueuedNonce = 1
+)
+
+// txPoolContent is the result of txpool_content, the pooled transactions by sender and nonce.
+type txPoolContent struct {
+	Pending map[common.Address]map[string]*RPCTransaction `json:"pending"`
+	Queued  map[common.Address]map[string]*RPCTransaction `json:"queued"`
+}
+
+// txPoolContentFrom is the result of txpool_contentFrom, the pooled transactions of a
+// sender by nonce.
+type txPoolContentFrom struct {
+	Pending map[string]*RPCTransaction `json:"pending"`
+	Queued  map[string]*RPCTransaction `json:"queued"`
+}
+
+// txPoolInspect is the result of txpool_inspect, a summary of the pooled transactions
+// by sender and nonce.
+type txPoolInspect struct {
+	Pending map[common.Address]map[string]string `json:"pending"`
+	Queued  map[common.Address]map[string]string `json:"queued"`
+}
+
+// bumpGasPrice returns gasPrice increased by percent.
+func bumpGasPrice(gasPrice *big.Int, percent int64) *big.Int {
+	bumped := new(big.Int).Mul(gasPrice, big.NewInt(100+percent))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// inspectSummary formats a transaction the way txpool_inspect does.
+func inspectSummary(tx *types.Transaction) string {
+	return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
+}
+
+// checkPooledTransaction checks that the pool lists tx under its nonce.
+func checkPooledTransaction(txs map[string]*RPCTransaction, tx *types.Transaction) error {
+	pooled, ok := txs[strconv.FormatUint(tx.Nonce(), 10)]
+	if !ok || pooled == nil {
+		return fmt.Errorf("no transaction pooled with nonce %d", tx.Nonce())
+	}
+	if pooled.Hash != tx.Hash() {
+		return fmt.Errorf("invalid tx hash at nonce %d: expected %s, actual %s", tx.Nonce(), tx.Hash(), pooled.Hash)
+	}
+	if pooled.GasPrice == nil || pooled.GasPrice.ToInt().Cmp(tx.GasPrice()) != 0 {
+		return fmt.Errorf("invalid gas price at nonce %d: expected %s, actual %v", tx.Nonce(), tx.GasPrice(), pooled.GasPrice)
+	}
+	return nil
+}
+
+// txPoolTestCases queue a transaction behind a nonce gap from a fresh sender, check how
+// the txpool methods list it, replace it and fill the gap so that it gets promoted. The
+// sender is not the test account, so the gap never stalls the other scenarios.
+var txPoolTestCases = []TestCase{
+	{
+		Key:      "TxPool Scenario: eth_sendRawTransaction (fund sender)",
+		Requires: []string{"chainId", "accountNonce", "gasPrice"},
+		Produces: []string{"txPoolSender"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			key, err := randomness.generateKey()
+			if err != nil {
+				return nil, err
+			}
+			rm.txPoolSender = Account{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}
+			rm.txPoolGasPrice = rm.gasPrice
+
+			value := new(big.Int).Mul(big.NewInt(21000*2*txPoolFunding), rm.gasPrice)
+			rm.txPoolFundingTx = transferTransaction(rm.nextNonce(), rm.txPoolSender.addr, value, rm.gasPrice, rm.account, rm.chainId)
+			rm.txPoolFundingTxSentAt = time.Now()
+			return NewRequest("eth_sendRawTransaction", []interface{}{encodeRawTransaction(rm.txPoolFundingTx)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			txHash, err := parseResponse[common.Hash](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *txHash != rm.txPoolFundingTx.Hash() {
+				return fmt.Errorf("invalid tx hash: expected %s, actual %s", rm.txPoolFundingTx.Hash(), txHash)
+			}
+			if _, err := rm.waitForInclusion(*txHash, rm.txPoolFundingTxSentAt); err != nil {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "TxPool Scenario: eth_sendRawTransaction (nonce gap)",
+		Requires: []string{"txPoolSender"},
+		Produces: []string{"txPoolQueuedTx"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			rm.txPoolQueuedTx = transferTransaction(txPoolQueuedNonce, rm.txPoolSender.addr, big.NewInt(0), rm.txPoolGasPrice, rm.txPoolSender, rm.chainId)
+			return NewRequest("eth_sendRawTransaction", []interface{}{encodeRawTransaction(rm.txPoolQueuedTx)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			txHash, err := parseResponse[common.Hash](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *txHash != rm.txPoolQueuedTx.Hash() {
+				return fmt.Errorf("invalid tx hash: expected %s, actual %s", rm.txPoolQueuedTx.Hash(), txHash)
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "TxPool Scenario: txpool_status",
+		Requires: []string{"txPoolQueuedTx"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("txpool_status", []interface{}{}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			status, err := parseResponse[map[string]hexutil.Uint](resp.Result)
+			if err != nil {
+				return err
+			}
+			if _, ok := (*status)["pending"]; !ok {
+				return fmt.Errorf("no pending count in %s", resp.Result)
+			}
+			if (*status)["queued"] == 0 {
+				return fmt.Errorf("expected at least one queued transaction, got %s", resp.Result)
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "TxPool Scenario: txpool_content",
+		Requires: []string{"txPoolQueuedTx"},
+		Produces: []string{"txPoolQueuedTxGasPrice"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("txpool_content", []interface{}{}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			content, err := parseResponse[txPoolContent](resp.Result)
+			if err != nil {
+				return err
+			}
+			if len(content.Pending[rm.txPoolSender.addr]) != 0 {
+				return fmt.Errorf("expected no pending transaction for %s, got %d", rm.txPoolSender.addr, len(content.Pending[rm.txPoolSender.addr]))
+			}
+			if err := checkPooledTransaction(content.Queued[rm.txPoolSender.addr], rm.txPoolQueuedTx); err != nil {
+				return fmt.Errorf("queued transactions of %s: %w", rm.txPoolSender.addr, err)
+			}
+			rm.txPoolQueuedTxGasPrice = rm.txPoolQueuedTx.GasPrice()
+			return nil
+		},
+	},
+	{
+		Key:      "TxPool Scenario: txpool_inspect",
+		Requires: []string{"txPoolQueuedTx"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("txpool_inspect", []interface{}{}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			inspect, err := parseResponse[txPoolInspect](resp.Result)
+			if err != nil {
+				return err
+			}
+			nonce := strconv.FormatUint(rm.txPoolQueuedTx.Nonce(), 10)
+			summary, ok := inspect.Queued[rm.txPoolSender.addr][nonce]
+			if !ok {
+				return fmt.Errorf("no queued transaction of %s with nonce %s", rm.txPoolSender.addr, nonce)
+			}
+			if expected := inspectSummary(rm.txPoolQueuedTx); summary != expected {
+				return fmt.Errorf("invalid summary: expected %q, actual %q", expected, summary)
+			}
+			return nil
+		},
+	},
+	// Both replacements can be sent in the same batch: whichever Bor handles first, the
+	// underpriced one does not bump the price enough over the other.
+	{
+		Key:         "TxPool Scenario: eth_sendRawTransaction (replacement underpriced)",
+		Requires:    []string{"txPoolQueuedTxGasPrice"},
+		ExpectError: &ExpectedError{Code: errCodeServerError, Message: "^replacement transaction underpriced"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			gasPrice := bumpGasPrice(rm.txPoolQueuedTxGasPrice, txPoolPriceBump/2)
+			rawTx := encodeRawTransaction(transferTransaction(txPoolQueuedNonce, rm.txPoolSender.addr, big.NewInt(1), gasPrice, rm.txPoolSender, rm.chainId))
+			return NewRequest("eth_sendRawTransaction", []interface{}{rawTx}), nil
+		},
+	},
+	{
+		Key:      "TxPool Scenario: eth_sendRawTransaction (replacement)",
+		Requires: []string{"txPoolQueuedTxGasPrice"},
+		Produces: []string{"txPoolReplacementTx"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			gasPrice := bumpGasPrice(rm.txPoolQueuedTxGasPrice, 2*txPoolPriceBump)
+			rm.txPoolReplacementTx = transferTransaction(txPoolQueuedNonce, rm.txPoolSender.addr, big.NewInt(2), gasPrice, rm.txPoolSender, rm.chainId)
+			return NewRequest("eth_sendRawTransaction", []interface{}{encodeRawTransaction(rm.txPoolReplacementTx)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			txHash, err := parseResponse[common.Hash](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *txHash != rm.txPoolReplacementTx.Hash() {
+				return fmt.Errorf("invalid tx hash: expected %s, actual %s", rm.txPoolReplacementTx.Hash(), txHash)
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "TxPool Scenario: txpool_contentFrom",
+		Requires: []string{"txPoolReplacementTx"},
+		Produces: []string{"txPoolGapNonce"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("txpool_contentFrom", []interface{}{rm.txPoolSender.addr}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			content, err := parseResponse[txPoolContentFrom](resp.Result)
+			if err != nil {
+				return err
+			}
+			if err := checkPooledTransaction(content.Queued, rm.txPoolReplacementTx); err != nil {
+				return fmt.Errorf("queued transactions: %w", err)
+			}
+
+			// The gap is the lowest nonce the sender has no transaction pooled with
+			var nonces []uint64
+			for _, txs := range []map[string]*RPCTransaction{content.Pending, content.Queued} {
+				for nonce := range txs {
+					n, err := strconv.ParseUint(nonce, 10, 64)
+					if err != nil {
+						return fmt.Errorf("invalid nonce %q: %w", nonce, err)
+					}
+					nonces = append(nonces, n)
+				}
+			}
+			sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+			rm.txPoolGapNonce = 0
+			for _, nonce := range nonces {
+				if nonce != rm.txPoolGapNonce {
+					break
+				}
+				rm.txPoolGapNonce++
+			}
+			if rm.txPoolGapNonce >= txPoolQueuedNonce {
+				return fmt.Errorf("expected a nonce gap below %d, got pooled nonces %v", txPoolQueuedNonce, nonces)
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "TxPool Scenario: eth_sendRawTransaction (fill nonce gap)",
+		Requires: []string{"txPoolGapNonce"},
+		Produces: []string{"txPoolPromotedTxBlockNumber"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			rm.txPoolGapTx = transferTransaction(rm.txPoolGapNonce, rm.txPoolSender.addr, big.NewInt(0), rm.txPoolGasPrice, rm.txPoolSender, rm.chainId)
+			rm.txPoolGapTxSentAt = time.Now()
+			return NewRequest("eth_sendRawTransaction", []interface{}{encodeRawTransaction(rm.txPoolGapTx)}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			txHash, err := parseResponse[common.Hash](resp.Result)
+			if err != nil {
+				return err
+			}
+			if *txHash != rm.txPoolGapTx.Hash() {
+				return fmt.Errorf("invalid tx hash: expected %s, actual %s", rm.txPoolGapTx.Hash(), txHash)
+			}
+			if _, err := rm.waitForInclusion(*txHash, rm.txPoolGapTxSentAt); err != nil {
+				return err
+			}
+
+			// The replacement is promoted once the gap is filled, it may be mined in a later block
+			receipt, _, err := waitForReceipt(rm.client, rm.txPoolReplacementTx.Hash(), rm.txPoolGapTxSentAt, inclusionTimeout, inclusionPollInterval)
+			if err != nil {
+				return fmt.Errorf("queued transaction not promoted: %w", err)
+			}
+			if receipt.BlockNumber == nil {
+				return fmt.Errorf("no block number in the receipt of %s", rm.txPoolReplacementTx.Hash())
+			}
+			rm.txPoolPromotedTxBlockNumber = receipt.BlockNumber.ToInt()
+			return nil
+		},
+	},
+	{
+		Key:      "TxPool Scenario: eth_getTransactionByHash (replaced transaction)",
+		Requires: []string{"txPoolPromotedTxBlockNumber"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("eth_getTransactionByHash", []interface{}{rm.txPoolQueuedTx.Hash()}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			if !bytes.Equal(resp.Result, []byte("null")) {
+				return fmt.Errorf("expected the replaced transaction to be dropped, got %s", resp.Result)
+			}
+			return nil
+		},
+	},
+	{
+		Key:      "TxPool Scenario: txpool_contentFrom (after promotion)",
+		Requires: []string{"txPoolPromotedTxBlockNumber"},
+		PrepareRequest: func(rm *ResponseMap) (*Request, error) {
+			return NewRequest("txpool_contentFrom", []interface{}{rm.txPoolSender.addr}), nil
+		},
+		HandleResponse: func(rm *ResponseMap, resp Response) error {
+			content, err := parseResponse[txPoolContentFrom](resp.Result)
+			if err != nil {
+				return err
+			}
+			if len(content.Pending) != 0 || len(content.Queued) != 0 {
+				return fmt.Errorf("expected no pooled transaction once mined, got %s", resp.Result)
+			}
+			return nil
+		},
+	},
+}
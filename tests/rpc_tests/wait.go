@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// inclusionTimeout bounds how long transaction scenarios wait for their transaction to be mined.
+	inclusionTimeout = 2 * time.Minute
+	// inclusionPollInterval is how often the receipt of a pending transaction is requested.
+	inclusionPollInterval = 250 * time.Millisecond
+)
+
+// TransactionInclusion records how long a transaction sent by a scenario took to be mined.
+type TransactionInclusion struct {
+	TxHash      common.Hash
+	BlockNumber *big.Int
+	Duration    time.Duration
+}
+
+func fetchBlockNumber(client RPCClient) (*big.Int, error) {
+	responses, err := client.Call([]Request{*NewRequest("eth_blockNumber", []interface{}{})})
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) != 1 {
+		return nil, fmt.Errorf("expected 1 response, got %d", len(responses))
+	}
+	if responses[0].Error != nil {
+		return nil, fmt.Errorf("request error; message: %s | code: %d", responses[0].Error.Message, responses[0].Error.Code)
+	}
+	parsed, err := parseResponse[string](responses[0].Result)
+	if err != nil {
+		return nil, err
+	}
+	return hexStringToBigInt(*parsed)
+}
+
+// waitForBlockNumber polls the node every pollInterval until it reaches target or the
+// timeout expires.
+func waitForBlockNumber(client RPCClient, target *big.Int, timeout, pollInterval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		current, err := fetchBlockNumber(client)
+		if err == nil && current.Cmp(target) >= 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("still at block %s after %s", current, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func fetchReceipt(client RPCClient, txHash common.Hash) (*RPCReceipt, error) {
+	responses, err := client.Call([]Request{*NewRequest("eth_getTransactionReceipt", []interface{}{txHash})})
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) != 1 {
+		return nil, fmt.Errorf("expected 1 response, got %d", len(responses))
+	}
+	if responses[0].Error != nil {
+		return nil, fmt.Errorf("request error; message: %s | code: %d", responses[0].Error.Message, responses[0].Error.Code)
+	}
+	return parseObject[RPCReceipt](responses[0].Result)
+}
+
+// waitForReceipt polls the receipt of txHash every pollInterval until the transaction is
+// mined or the timeout expires. The returned inclusion is measured from sentAt, the time
+// the transaction was handed to the node.
+func waitForReceipt(client RPCClient, txHash common.Hash, sentAt time.Time, timeout, pollInterval time.Duration) (*RPCReceipt, *TransactionInclusion, error) {
+	if client == nil {
+		return nil, nil, errors.New("no client to poll the receipt with")
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		receipt, err := fetchReceipt(client, txHash)
+		if err == nil && receipt != nil && receipt.BlockNumber != nil {
+			inclusion := &TransactionInclusion{
+				TxHash:      txHash,
+				BlockNumber: receipt.BlockNumber.ToInt(),
+				Duration:    time.Since(sentAt),
+			}
+			return receipt, inclusion, nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return nil, nil, fmt.Errorf("transaction %s not mined after %s: %w", txHash, timeout, err)
+			}
+			return nil, nil, fmt.Errorf("transaction %s not mined after %s", txHash, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// waitForInclusion waits for txHash to be mined, records how long it took and
+// returns its receipt.
+func (rm *ResponseMap) waitForInclusion(txHash common.Hash, sentAt time.Time) (*RPCReceipt, error) {
+	receipt, inclusion, err := waitForReceipt(rm.client, txHash, sentAt, inclusionTimeout, inclusionPollInterval)
+	if err != nil {
+		return nil, err
+	}
+	rm.inclusions = append(rm.inclusions, *inclusion)
+	return receipt, nil
+}
+
+func printInclusions(inclusions []TransactionInclusion) {
+	fmt.Printf("\n⛏️  Transaction Inclusion Times:\n")
+	for _, inclusion := range inclusions {
+		fmt.Printf("  %s: mined in block %s after %s\n", inclusion.TxHash, inclusion.BlockNumber, inclusion.Duration)
+	}
+}